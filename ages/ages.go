@@ -0,0 +1,295 @@
+// Package ages parses the ages written in Spanish by the fiscalías, e.g.
+// "15 Años, 4 Meses", "8 meses", "tres años", "entre 30 y 35 años" or
+// "aprox. 40 años", and computes the age of a person on a date.
+package ages
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midir99/rastreadora/internal/fold"
+)
+
+// Span is a length of time in years, months and days, e.g. "1 año 6 meses".
+// The months are less than 12, the days aren't carried to the months.
+type Span struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// TotalMonths returns the completed months of s, the days are counted as
+// the twelfth part of a year of 365 days.
+func (s Span) TotalMonths() int {
+	return s.Years*12 + s.Months + s.Days*12/365
+}
+
+func (s *Span) add(n float64, u unit) {
+	whole := math.Floor(n)
+	frac := n - whole
+	switch u {
+	case unitYear:
+		s.Years += int(whole)
+		s.Months += int(math.Round(frac * 12))
+	case unitMonth:
+		s.Months += int(whole)
+		s.Days += int(math.Round(frac * 30))
+	case unitWeek:
+		s.Days += int(math.Round(n * 7))
+	case unitDay:
+		s.Days += int(math.Round(n))
+	}
+	s.Years += s.Months / 12
+	s.Months %= 12
+}
+
+// Age is a parsed age. Min and Max are the ends of a range, e.g. "entre 30 y
+// 35 años"; they are the same for the other ages.
+type Age struct {
+	Min Span
+	Max Span
+	// Approximate tells that the age was qualified, e.g. "aprox. 40 años" or
+	// "alrededor de 40 años".
+	Approximate bool
+	// AtLeast tells that Min is a lower bound and the range has no upper
+	// end, e.g. "más de 40 años".
+	AtLeast bool
+}
+
+// IsRange tells whether the age is a range, open or not.
+func (a Age) IsRange() bool {
+	return a.Min != a.Max || a.AtLeast
+}
+
+// YearsAndMonths returns the age in completed years and the months on top
+// of them: the middle of the range for the ranges and the lower bound for
+// the open ones.
+func (a Age) YearsAndMonths() (int, int) {
+	months := a.Min.TotalMonths()
+	if !a.AtLeast {
+		months = (months + a.Max.TotalMonths()) / 2
+	}
+	return months / 12, months % 12
+}
+
+// Years returns the completed years of the age, see YearsAndMonths.
+func (a Age) Years() int {
+	years, _ := a.YearsAndMonths()
+	return years
+}
+
+type unit int
+
+const (
+	unitYear unit = iota + 1
+	unitMonth
+	unitWeek
+	unitDay
+)
+
+var units = map[string]unit{
+	"ano":     unitYear,
+	"anos":    unitYear,
+	"anio":    unitYear,
+	"anios":   unitYear,
+	"mes":     unitMonth,
+	"meses":   unitMonth,
+	"semana":  unitWeek,
+	"semanas": unitWeek,
+	"dia":     unitDay,
+	"dias":    unitDay,
+}
+
+// writtenNumbers are the numbers written in words, without accents.
+var writtenNumbers = map[string]float64{
+	"medio":        0.5,
+	"media":        0.5,
+	"cero":         0,
+	"un":           1,
+	"uno":          1,
+	"una":          1,
+	"dos":          2,
+	"tres":         3,
+	"cuatro":       4,
+	"cinco":        5,
+	"seis":         6,
+	"siete":        7,
+	"ocho":         8,
+	"nueve":        9,
+	"diez":         10,
+	"once":         11,
+	"doce":         12,
+	"trece":        13,
+	"catorce":      14,
+	"quince":       15,
+	"dieciseis":    16,
+	"diecisiete":   17,
+	"dieciocho":    18,
+	"diecinueve":   19,
+	"veinte":       20,
+	"veintiun":     21,
+	"veintiuno":    21,
+	"veintiuna":    21,
+	"veintidos":    22,
+	"veintitres":   23,
+	"veinticuatro": 24,
+	"veinticinco":  25,
+	"veintiseis":   26,
+	"veintisiete":  27,
+	"veintiocho":   28,
+	"veintinueve":  29,
+	"treinta":      30,
+	"cuarenta":     40,
+	"cincuenta":    50,
+	"sesenta":      60,
+	"setenta":      70,
+	"ochenta":      80,
+	"noventa":      90,
+	"cien":         100,
+}
+
+var (
+	// approximateWords qualify an age.
+	approximateWords = map[string]bool{
+		"aprox":           true,
+		"aproximadamente": true,
+		"aproximada":      true,
+		"aproximado":      true,
+		"alrededor":       true,
+		"cerca":           true,
+		"unos":            true,
+		"unas":            true,
+	}
+	// rangeWords join the ends of a range, e.g. "30 a 35" or "30-35".
+	rangeWords = map[string]bool{
+		"a":  true,
+		"al": true,
+		"y":  true,
+		"o":  true,
+		"-":  true,
+	}
+	ignoredWords = map[string]bool{
+		"de":        true,
+		"edad":      true,
+		"con":       true,
+		"y":         true,
+		"entre":     true,
+		"cumplidos": true,
+	}
+)
+
+var tokenRe = regexp.MustCompile(`\d+(?:[.,]\d+)?|[a-z]+|-`)
+
+// Parse parses an age made of numbers of years, months, weeks or days, e.g.
+// "15 Años, 4 Meses", "8 meses" or "año y medio". The numbers can be written
+// in words ("tres años", "treinta y cinco años"), a range has two numbers
+// ("entre 30 y 35 años", "30-35 años"), "más de 40 años" is an open range
+// (see AtLeast) and the qualifiers like "aprox." make the age Approximate. A
+// number without a unit is in years.
+func Parse(value string) (Age, error) {
+	var (
+		age       Age
+		pending   []float64
+		rangeOpen bool
+		lastUnit  = unitYear
+		parsed    bool
+	)
+	tokens := tokenRe.FindAllString(fold.Fold(value), -1)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if n, next, ok := readNumber(tokens, i); ok {
+			if len(pending) == 2 || len(pending) == 1 && !rangeOpen {
+				return Age{}, fmt.Errorf("unable to parse age %s (a number has no unit)", value)
+			}
+			pending = append(pending, n)
+			rangeOpen = false
+			i = next - 1
+			continue
+		}
+		switch {
+		case units[token] != 0:
+			if len(pending) == 0 {
+				if token != "ano" && token != "mes" && token != "semana" && token != "dia" {
+					return Age{}, fmt.Errorf("unable to parse age %s (%s has no number)", value, token)
+				}
+				// "medio año" has its number, but "año y medio" is one year.
+				pending = []float64{1}
+			}
+			lastUnit = units[token]
+			addPending(&age, pending, lastUnit)
+			pending, parsed = nil, true
+		case rangeWords[token] && len(pending) == 1:
+			rangeOpen = true
+		case approximateWords[token]:
+			age.Approximate = true
+		case token == "mas":
+			age.AtLeast = true
+		case ignoredWords[token] || rangeWords[token]:
+		default:
+			return Age{}, fmt.Errorf("unable to parse age %s (unknown word: %s)", value, token)
+		}
+	}
+	if rangeOpen {
+		return Age{}, fmt.Errorf("unable to parse age %s (the range has no end)", value)
+	}
+	if len(pending) != 0 {
+		// "15", "30-35" or the "medio" of "un año y medio", in the unit of
+		// the last number or else in years.
+		addPending(&age, pending, lastUnit)
+		parsed = true
+	}
+	if !parsed {
+		return Age{}, fmt.Errorf("unable to parse age %s", value)
+	}
+	return age, nil
+}
+
+func addPending(age *Age, pending []float64, u unit) {
+	age.Min.add(pending[0], u)
+	age.Max.add(pending[len(pending)-1], u)
+}
+
+// readNumber reads the number that starts at tokens[i] and returns it with
+// the index of the token that follows it.
+func readNumber(tokens []string, i int) (float64, int, bool) {
+	if n, err := strconv.ParseFloat(strings.Replace(tokens[i], ",", ".", 1), 64); err == nil {
+		return n, i + 1, true
+	}
+	n, ok := writtenNumbers[tokens[i]]
+	if !ok {
+		return 0, i, false
+	}
+	// "treinta y cinco"
+	if n >= 30 && n < 100 && i+2 < len(tokens) && tokens[i+1] == "y" {
+		if units, ok := writtenNumbers[tokens[i+2]]; ok && units >= 1 && units <= 9 {
+			return n + units, i + 3, true
+		}
+	}
+	return n, i + 1, true
+}
+
+// Between returns the age on the date to of a person born on the date from,
+// counted in the calendar: from the 15th of March to the 14th of April is 30
+// days and to the 15th is a month. It is zero if to is before from.
+func Between(from, to time.Time) Span {
+	if to.Before(from) {
+		return Span{}
+	}
+	y1, m1, d1 := from.Date()
+	y2, m2, d2 := to.Date()
+	s := Span{Years: y2 - y1, Months: int(m2) - int(m1), Days: d2 - d1}
+	if s.Days < 0 {
+		s.Months--
+		// The days of the month before to.
+		s.Days += time.Date(y2, m2, 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+	if s.Months < 0 {
+		s.Years--
+		s.Months += 12
+	}
+	return s
+}
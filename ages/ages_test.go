@@ -0,0 +1,155 @@
+package ages
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		value             string
+		wantedMin         Span
+		wantedMax         Span
+		wantedApproximate bool
+	}{
+		{"15 Años, 4 Meses", Span{Years: 15, Months: 4}, Span{Years: 15, Months: 4}, false},
+		{"8 meses", Span{Months: 8}, Span{Months: 8}, false},
+		{"1 año 6 meses", Span{Years: 1, Months: 6}, Span{Years: 1, Months: 6}, false},
+		{"18 meses", Span{Years: 1, Months: 6}, Span{Years: 1, Months: 6}, false},
+		{"2 semanas", Span{Days: 14}, Span{Days: 14}, false},
+		{"10 días", Span{Days: 10}, Span{Days: 10}, false},
+		{"15", Span{Years: 15}, Span{Years: 15}, false},
+		{"15 años de edad", Span{Years: 15}, Span{Years: 15}, false},
+		{"tres años", Span{Years: 3}, Span{Years: 3}, false},
+		{"treinta y cinco años", Span{Years: 35}, Span{Years: 35}, false},
+		{"un año y medio", Span{Years: 1, Months: 6}, Span{Years: 1, Months: 6}, false},
+		{"año y medio", Span{Years: 1, Months: 6}, Span{Years: 1, Months: 6}, false},
+		{"medio año", Span{Months: 6}, Span{Months: 6}, false},
+		{"2.5 años", Span{Years: 2, Months: 6}, Span{Years: 2, Months: 6}, false},
+		{"entre 30 y 35 años", Span{Years: 30}, Span{Years: 35}, false},
+		{"entre treinta y cuarenta años", Span{Years: 30}, Span{Years: 40}, false},
+		{"30-35 años", Span{Years: 30}, Span{Years: 35}, false},
+		{"de 6 a 8 meses", Span{Months: 6}, Span{Months: 8}, false},
+		{"aprox. 40 años", Span{Years: 40}, Span{Years: 40}, true},
+		{"40 años (aproximadamente)", Span{Years: 40}, Span{Years: 40}, true},
+		{"alrededor de 25 a 30 años", Span{Years: 25}, Span{Years: 30}, true},
+		// A lower bound is an open range, not an approximation.
+		{"más de 40 años", Span{Years: 40}, Span{Years: 40}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			age, err := Parse(tc.value)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if age.Min != tc.wantedMin || age.Max != tc.wantedMax {
+				t.Errorf("got %v to %v; want %v to %v", age.Min, age.Max, tc.wantedMin, tc.wantedMax)
+			}
+			if age.Approximate != tc.wantedApproximate {
+				t.Errorf("got approximate %t; want %t", age.Approximate, tc.wantedApproximate)
+			}
+		})
+	}
+}
+
+func TestParseAtLeast(t *testing.T) {
+	age, err := Parse("más de 40 años")
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if !age.AtLeast || !age.IsRange() {
+		t.Errorf("got AtLeast %t and IsRange %t; want an open range", age.AtLeast, age.IsRange())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"no especificada",
+		"años",
+		"15 4",
+		"entre 30 y",
+		"15 años 4 meses 3 días y algo",
+	} {
+		t.Run(value, func(t *testing.T) {
+			if age, err := Parse(value); err == nil {
+				t.Errorf("got %v; want an error", age)
+			}
+		})
+	}
+}
+
+func TestAgeYears(t *testing.T) {
+	testCases := []struct {
+		value  string
+		wanted int
+	}{
+		{"15 Años, 4 Meses", 15},
+		{"8 meses", 0},
+		{"400 días", 1},
+		{"entre 30 y 35 años", 32},
+		{"más de 40 años", 40},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			age, err := Parse(tc.value)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if got := age.Years(); got != tc.wanted {
+				t.Errorf("got %d; want %d", got, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestAgeYearsAndMonths(t *testing.T) {
+	testCases := []struct {
+		value        string
+		wantedYears  int
+		wantedMonths int
+	}{
+		{"8 meses", 0, 8},
+		{"1 año 6 meses", 1, 6},
+		{"entre 30 y 35 años", 32, 6},
+		{"más de 40 años", 40, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			age, err := Parse(tc.value)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if years, months := age.YearsAndMonths(); years != tc.wantedYears || months != tc.wantedMonths {
+				t.Errorf("got %d years and %d months; want %d and %d", years, months, tc.wantedYears, tc.wantedMonths)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		name   string
+		from   time.Time
+		to     time.Time
+		wanted Span
+	}{
+		{"birthday", date(2000, time.March, 15), date(2022, time.March, 15), Span{Years: 22}},
+		{"day before the birthday", date(2000, time.March, 15), date(2022, time.March, 14), Span{Years: 21, Months: 11, Days: 27}},
+		{"months", date(2021, time.November, 30), date(2022, time.May, 2), Span{Months: 5, Days: 2}},
+		// The birthday of the 29th of February is the 1st of March in the
+		// common years.
+		{"leap day", date(2004, time.February, 29), date(2022, time.March, 1), Span{Years: 18}},
+		{"before", date(2022, time.March, 15), date(2000, time.March, 15), Span{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Between(tc.from, tc.to); got != tc.wanted {
+				t.Errorf("got %v; want %v", got, tc.wanted)
+			}
+		})
+	}
+}
@@ -1,72 +1,128 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
-	"github.com/midir99/rastreadora/doc"
 	"github.com/midir99/rastreadora/mpp"
 	"github.com/midir99/rastreadora/ws"
 )
 
-type AlertType string
+// ExitIncomplete is the exit status used when the run was interrupted or
+// timed out; whatever was collected until then is still written.
+const ExitIncomplete = 2
 
-const (
-	AlertTypeCdmxCustom    = "cdmx-custom"
-	AlertTypeChisHasVistoA = "chis-hasvistoa"
-	AlertTypeGroAlba       = "gro-alba"
-	AlertTypeGroAmber      = "gro-amber"
-	AlertTypeGroHasVistoA  = "gro-hasvistoa"
-	AlertTypeMorAmber      = "mor-amber"
-	AlertTypeMorCustom     = "mor-custom"
-)
+type AlertType string
 
 func AlertTypesAvailable() []AlertType {
-	return []AlertType{
-		AlertTypeCdmxCustom,
-		AlertTypeChisHasVistoA,
-		AlertTypeGroAlba,
-		AlertTypeGroAmber,
-		AlertTypeGroHasVistoA,
-		AlertTypeMorAmber,
-		AlertTypeMorCustom,
+	alertTypes := []AlertType{}
+	for _, s := range ws.Sources() {
+		alertTypes = append(alertTypes, AlertType(s.Name()))
 	}
+	return alertTypes
 }
 
 var usageTemplate = `rastreadora is a tool for scraping missing person posters data.
 
 Usage:
 
-    rastreadora [-o output] <alert-type> <from> [until]
+    rastreadora [-o output] <alert-type> <from> [until|auto]
 
 Arguments:
 
-    alert-type (string): the type of alerts that you want to collect:{{range .AlertTypes}}
-                         - {{.}}{{end}}
+    alert-type (string): the type of alerts that you want to collect:{{range .Sources}}
+                         - {{printf "%-15s" .Name}} {{.State}}{{end}}
     from    (number):    the page number to start scraping missing person posters data.
     until   (number):    the page number to stop scraping missing person posters data, if omitted
                          the program will only scrap data from the page number specified by the
-                         <from> argument.
+                         <from> argument. Use "auto" to keep scraping until the last page, which
+                         is detected by a missing page (404), an empty listing, a page that
+                         repeats the previous one or the pagination links of the site.
 
 Flags:
 
-    -o           (string): the filename where the data will be stored, if omitted the data will be
-                           dumped in STDOUT.
-    -skip-verify (bool):   skip the verification of the server's certificate chain and hostname.
-    -V           (bool):   print the version of the program.
-    -h           (bool):   print this usage message.
+    -o               (string):   the filename where the data will be stored, if omitted the data
+                                 will be dumped in STDOUT.
+    -skip-verify     (bool):     skip the verification of the server's certificate chain and
+                                 hostname.
+    -user-agent      (string):   the User-Agent header sent in every request, by default it
+                                 identifies the program and its version.
+    -request-timeout (duration): the maximum duration of a single request, 0 means no limit (default
+                                 30s).
+    -max-idle-conns  (number):   the number of idle connections kept open for every host (default
+                                 10).
+    -proxy           (string):   the URL of the HTTP proxy used for every request, if omitted the
+                                 proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+                                 environment variables.
+    -gzip            (bool):     request gzip compressed responses (default true), use -gzip=false
+                                 to disable it.
+    -max-redirects   (number):   the number of redirects followed before giving up, -1 disables
+                                 redirects (default 10).
+    -retries         (number):   the number of times a request that failed with a server error (5xx,
+                                 408, 429), a timeout or a dropped connection is repeated (default
+                                 3). Missing pages, unknown hosts and certificate errors are never
+                                 retried.
+    -retry-delay     (duration): the wait before the first retry, it doubles after every failed
+                                 attempt up to 30s and half of it is random (default 1s). A longer
+                                 Retry-After header sent by the server takes precedence, but the
+                                 request is not retried if the server asks to wait over 30s.
+    -concurrency     (number):   the number of pages scraped at the same time (default 4).
+    -rps             (number):   the maximum number of requests sent to a host per second, shared by
+                                 the listing pages and the pages of every entry; 0 means no limit
+                                 (default 2).
+    -min-delay       (duration): the minimum time between two requests to the same host (default
+                                 0s).
+    -record          (string):   the directory where every HTTP exchange (listing and entry pages)
+                                 is saved, so the run can be repeated later with -replay. The pages
+                                 served from -cache are saved too.
+    -replay          (string):   the directory of HTTP exchanges saved with -record, they are used
+                                 instead of the network; requests that were not recorded fail.
+    -cache           (string):   the directory of the persistent cache of pages. Cached pages are
+                                 revalidated with the server (If-None-Match, If-Modified-Since) and
+                                 only downloaded again when they changed.
+    -cache-max-age   (duration): for how long a cached page is used without asking the server
+                                 whether it changed (default 0s). Some alert types keep the pages of
+                                 each entry for longer, e.g. the chis-hasvistoa profiles are kept
+                                 for 30 days.
+    -offline         (bool):     serve every page from the cache given with -cache and never use the
+                                 network; pages that are not cached fail.
+    -timeout         (duration): the maximum duration of the whole run, e.g. 90s or 5m; if omitted
+                                 there is no limit. When the time runs out (or when the program
+                                 receives Ctrl-C) the pending requests are cancelled, the data
+                                 collected until then is written and the program exits with status
+                                 2. A second Ctrl-C quits at once without writing anything.
+    -sources         (string):   the directory of YAML (.yaml, .yml) and JSON (.json) files that
+                                 define more alert types, each file describes the listing pages of a
+                                 source: the URL with a {page} placeholder, the selector of the
+                                 entries and where every field is found.
+    -V               (bool):     print the version of the program.
+    -h               (bool):     print this usage message.
 `
 
 func Usage() {
+	// With "-sources dir -h" the directory is known before the usage is
+	// printed, so its alert types are listed too.
+	if f := flag.Lookup("sources"); f != nil {
+		if err := loadSources(f.Value.String()); err != nil {
+			fmt.Fprintf(flag.CommandLine.Output(), "unable to load the alert types of -sources: %s\n", err)
+		}
+	}
 	templateData := struct {
-		AlertTypes []AlertType
-	}{AlertTypesAvailable()}
+		Sources []ws.Source
+	}{ws.Sources()}
 	tmpl := template.Must(template.New("usage").Parse(usageTemplate))
 	err := tmpl.Execute(flag.CommandLine.Output(), templateData)
 	if err != nil {
@@ -74,38 +130,136 @@ func Usage() {
 	}
 }
 
+// loadedSources is the directory of -sources already loaded, by the usage
+// or by ParseArgs.
+var loadedSources string
+
+// loadSources registers the alert types defined in dir, once.
+func loadSources(dir string) error {
+	if dir == "" || dir == loadedSources {
+		return nil
+	}
+	if _, err := ws.LoadSources(dir); err != nil {
+		return err
+	}
+	loadedSources = dir
+	return nil
+}
+
 type Args struct {
 	AlertType    AlertType
 	PageFrom     uint64
 	PageUntil    uint64
+	Auto         bool
 	Out          string
 	SkipVerify   bool
+	Timeout      time.Duration
+	UserAgent    string
+	ReqTimeout   time.Duration
+	MaxIdleConns int
+	Proxy        *url.URL
+	Gzip         bool
+	MaxRedirects int
+	Retries      int
+	RetryDelay   time.Duration
+	Concurrency  int
+	Rps          float64
+	MinDelay     time.Duration
+	Record       string
+	Replay       string
+	Cache        string
+	CacheMaxAge  time.Duration
+	Offline      bool
+	Sources      string
 	PrintVersion bool
 }
 
+func (a *Args) ClientConfig() ws.ClientConfig {
+	return ws.ClientConfig{
+		UserAgent:           a.UserAgent,
+		Timeout:             a.ReqTimeout,
+		MaxIdleConnsPerHost: a.MaxIdleConns,
+		Proxy:               a.Proxy,
+		DisableCompression:  !a.Gzip,
+		MaxRedirects:        a.MaxRedirects,
+		SkipVerify:          a.SkipVerify,
+		Retry: ws.RetryPolicy{
+			Attempts:  a.Retries + 1,
+			BaseDelay: a.RetryDelay,
+			MaxDelay:  ws.DefaultRetryPolicy().MaxDelay,
+		},
+		RateLimit: ws.RateLimit{
+			RequestsPerSecond: a.Rps,
+			MinDelay:          a.MinDelay,
+		},
+		RecordDir: a.Record,
+		ReplayDir: a.Replay,
+		CacheDir:  a.Cache,
+		Offline:   a.Offline,
+		Logger:    log.Default(),
+	}
+}
+
 func ParseArgs() (*Args, error) {
 	args := Args{}
 	flag.StringVar(&args.Out, "o", "", "the filename where the data will be stored, if omitted the data will be dumped in STDOUT.")
 	flag.BoolVar(&args.SkipVerify, "skip-verify", false, "skip the verification of the server's certificate chain and hostname.")
+	flag.DurationVar(&args.Timeout, "timeout", 0, "the maximum duration of the whole run, e.g. 90s or 5m; if omitted there is no limit.")
+	defaults := ws.DefaultClientConfig()
+	var proxy string
+	flag.StringVar(&args.UserAgent, "user-agent", "rastreadora/"+Version+" (+https://github.com/midir99/rastreadora)", "the User-Agent header sent in every request.")
+	flag.DurationVar(&args.ReqTimeout, "request-timeout", defaults.Timeout, "the maximum duration of a single request, 0 means no limit.")
+	flag.IntVar(&args.MaxIdleConns, "max-idle-conns", defaults.MaxIdleConnsPerHost, "the number of idle connections kept open for every host.")
+	flag.StringVar(&proxy, "proxy", "", "the URL of the HTTP proxy used for every request.")
+	flag.BoolVar(&args.Gzip, "gzip", !defaults.DisableCompression, "request gzip compressed responses.")
+	flag.IntVar(&args.MaxRedirects, "max-redirects", defaults.MaxRedirects, "the number of redirects followed before giving up, -1 disables redirects.")
+	flag.IntVar(&args.Retries, "retries", defaults.Retry.Attempts-1, "the number of times a failed request is repeated.")
+	flag.DurationVar(&args.RetryDelay, "retry-delay", defaults.Retry.BaseDelay, "the wait before the first retry, it doubles after every failed attempt.")
+	flag.IntVar(&args.Concurrency, "concurrency", 4, "the number of pages scraped at the same time.")
+	flag.Float64Var(&args.Rps, "rps", defaults.RateLimit.RequestsPerSecond, "the maximum number of requests sent to a host per second, 0 means no limit.")
+	flag.DurationVar(&args.MinDelay, "min-delay", defaults.RateLimit.MinDelay, "the minimum time between two requests to the same host.")
+	flag.StringVar(&args.Record, "record", "", "the directory where every HTTP exchange is saved.")
+	flag.StringVar(&args.Replay, "replay", "", "the directory of saved HTTP exchanges used instead of the network.")
+	flag.StringVar(&args.Cache, "cache", "", "the directory of the persistent cache of pages.")
+	flag.DurationVar(&args.CacheMaxAge, "cache-max-age", 0, "for how long a cached page is used without asking the server whether it changed.")
+	flag.BoolVar(&args.Offline, "offline", false, "serve every page from the cache and never use the network.")
+	flag.StringVar(&args.Sources, "sources", "", "the directory of the YAML and JSON files that define more alert types.")
 	flag.BoolVar(&args.PrintVersion, "V", false, "print the version of the program.")
 	flag.Usage = Usage
 	flag.Parse()
 	if args.PrintVersion {
 		return &args, nil
 	}
+	// Validate the "proxy" flag
+	if proxy != "" {
+		p, err := url.Parse(proxy)
+		if err != nil || p.Scheme == "" || p.Host == "" {
+			return nil, fmt.Errorf("\"%s\" is not a valid URL for -proxy", proxy)
+		}
+		args.Proxy = p
+	}
+	// Validate the "concurrency" flag
+	if args.Concurrency < 1 {
+		return nil, fmt.Errorf("-concurrency value must be greater than 0")
+	}
+	// Validate the "record" and "replay" flags
+	if args.Record != "" && args.Record == args.Replay {
+		return nil, fmt.Errorf("-record and -replay can't use the same directory")
+	}
+	// Validate the "offline" flag
+	if args.Offline && args.Cache == "" {
+		return nil, fmt.Errorf("-offline requires the -cache flag")
+	}
+	// Load the alert types of the "sources" flag
+	if err := loadSources(args.Sources); err != nil {
+		return nil, fmt.Errorf("unable to load the alert types of -sources: %s", err)
+	}
 	// Validate the "alert-type" argument
 	args.AlertType = AlertType(flag.Arg(0))
 	if args.AlertType == "" {
 		return nil, fmt.Errorf("<alert-type> argument cannot be empty")
 	}
-	alertIsValid := false
-	for _, s := range AlertTypesAvailable() {
-		if args.AlertType == s {
-			alertIsValid = true
-			break
-		}
-	}
-	if !alertIsValid {
+	if _, ok := ws.Lookup(string(args.AlertType)); !ok {
 		return nil, fmt.Errorf("\"%s\" is not a valid choice for <alert-type>", args.AlertType)
 	}
 	// Validate the "from" argument
@@ -120,6 +274,9 @@ func ParseArgs() (*Args, error) {
 	// Validate the "until" argument
 	if flag.Arg(2) == "" {
 		args.PageUntil = args.PageFrom
+	} else if flag.Arg(2) == "auto" {
+		args.Auto = true
+		args.PageUntil = args.PageFrom
 	} else {
 		pU, err := strconv.ParseUint(flag.Arg(2), 10, 0)
 		if err != nil {
@@ -134,29 +291,18 @@ func ParseArgs() (*Args, error) {
 	return &args, nil
 }
 
+const Version = "0.6.0"
+
 func PrintVersion() {
-	fmt.Println("rastreadora v0.6.0")
-}
-
-func SelectScraperFuncs(alertType AlertType) (func(*doc.Doc) ([]mpp.MissingPersonPoster, map[int]error), func(uint64) string, error) {
-	switch alertType {
-	case AlertTypeCdmxCustom:
-		return ws.ScrapeCdmxCustomAlerts, ws.MakeCdmxCustomUrl, nil
-	case AlertTypeChisHasVistoA:
-		return ws.ScrapeChisHasVistoAAlerts, ws.MakeChisHasVistoAUrl, nil
-	case AlertTypeGroAlba:
-		return ws.ScrapeGroAlbaAlerts, ws.MakeGroAlbaUrl, nil
-	case AlertTypeGroAmber:
-		return ws.ScrapeGroAmberAlerts, ws.MakeGroAmberUrl, nil
-	case AlertTypeGroHasVistoA:
-		return ws.ScrapeGroHasVistoAAlerts, ws.MakeGroHasVistoAUrl, nil
-	case AlertTypeMorAmber:
-		return ws.ScrapeMorAmberAlerts, ws.MakeMorAmberUrl, nil
-	case AlertTypeMorCustom:
-		return ws.ScrapeMorCustomAlerts, ws.MakeMorCustomUrl, nil
-	default:
-		return nil, nil, fmt.Errorf("invalid alert-type %v", alertType)
+	fmt.Println("rastreadora v" + Version)
+}
+
+func SelectSource(alertType AlertType) (ws.Source, error) {
+	source, ok := ws.Lookup(string(alertType))
+	if !ok {
+		return nil, fmt.Errorf("invalid alert-type %v", alertType)
 	}
+	return source, nil
 }
 
 func entryLegend(entries int) string {
@@ -173,27 +319,224 @@ func mppLegend(mpps int) string {
 	return "missing person posters"
 }
 
-func Scrape(pageUrl string, scraper func(*doc.Doc) ([]mpp.MissingPersonPoster, map[int]error), skipVerify bool, ch chan []mpp.MissingPersonPoster) {
-	doc, err := ws.RetrieveDocument(pageUrl, skipVerify)
+func errsLegend(errs map[int]error) string {
+	messages := []string{}
+	for entryNumber, err := range errs {
+		messages = append(messages, fmt.Sprintf("entry #%d: %s", entryNumber, err))
+	}
+	return strings.Join(messages, ",")
+}
+
+func Enrich(ctx context.Context, fetcher ws.Fetcher, source ws.Source, mpps []mpp.MissingPersonPoster) map[int]error {
+	errs := make(map[int]error)
+	enricher, ok := source.(ws.Enricher)
+	if !ok {
+		return errs
+	}
+	for i := range mpps {
+		if ctx.Err() != nil {
+			errs[i+1] = ctx.Err()
+			continue
+		}
+		if err := enricher.Enrich(ctx, fetcher, &mpps[i]); err != nil {
+			errs[i+1] = err
+		}
+	}
+	return errs
+}
+
+// Page holds what was collected from a single listing page. Err is not nil
+// when the page could not be retrieved or when the run was cancelled before
+// all of its entries were completed. Last is true when the listing tells
+// there are no pages after this one.
+type Page struct {
+	Num  uint64
+	Url  string
+	Mpps []mpp.MissingPersonPoster
+	Last bool
+	Err  error
+}
+
+func Scrape(ctx context.Context, fetcher ws.Fetcher, pageUrl string, source ws.Source) Page {
+	doc, err := fetcher.RetrieveDocument(ctx, pageUrl)
 	if err != nil {
 		log.Printf("0 entries collected from %s; %s", pageUrl, err)
-		ch <- []mpp.MissingPersonPoster{}
-		return
+		return Page{Url: pageUrl, Mpps: []mpp.MissingPersonPoster{}, Err: err}
 	}
-	mpps, errs := scraper(doc)
+	mpps, errs := source.Scrape(doc)
 	mppsLen := len(mpps)
 	entryWord := entryLegend(mppsLen)
 	if errsLen := len(errs); errsLen > 0 {
-		messages := []string{}
-		for entryNumber, err := range errs {
-			messages = append(messages, fmt.Sprintf("entry #%d: %s", entryNumber, err))
-		}
-		message := strings.Join(messages, ",")
-		log.Printf("%d %s collected from %s; unable to retrieve %d, details: %s", mppsLen, entryWord, pageUrl, errsLen, message)
+		log.Printf("%d %s collected from %s; unable to retrieve %d, details: %s", mppsLen, entryWord, pageUrl, errsLen, errsLegend(errs))
 	} else {
 		log.Printf("%d %s collected from %s", mppsLen, entryWord, pageUrl)
 	}
-	ch <- mpps
+	last := mppsLen == 0 && len(errs) == 0
+	if paginator, ok := source.(ws.Paginator); ok && paginator.LastPage(doc) {
+		last = true
+	}
+	if errs := Enrich(ctx, fetcher, source, mpps); len(errs) > 0 {
+		log.Printf("unable to complete the data of %d %s from %s, details: %s", len(errs), entryLegend(len(errs)), pageUrl, errsLegend(errs))
+	}
+	return Page{Url: pageUrl, Mpps: mpps, Last: last, Err: ctx.Err()}
+}
+
+// Result is what a run collected. IncompletePages counts the pages that were
+// interrupted because the run was cancelled or timed out.
+type Result struct {
+	Mpps            []mpp.MissingPersonPoster
+	Pages           uint64
+	IncompletePages uint64
+}
+
+// maxFailedPages is the number of consecutive pages that may fail before an
+// automatic pagination gives up.
+const maxFailedPages = 3
+
+// pagination decides, looking at the pages in order, where an automatic
+// pagination ends: at a missing page (404), at a page that repeats the
+// previous one, at an empty listing or at the page the source says is the
+// last one.
+type pagination struct {
+	ended       bool
+	failed      int
+	prevEntries string
+}
+
+// include tells whether the page belongs to the listing, it must be called
+// with the pages in order.
+func (p *pagination) include(page Page) bool {
+	if p.ended {
+		return false
+	}
+	var statusErr *ws.StatusError
+	switch {
+	case errors.As(page.Err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		log.Printf("%s was not found, it is past the last page", page.Url)
+		p.ended = true
+		return false
+	case page.Err != nil:
+		p.failed++
+		if p.failed >= maxFailedPages {
+			log.Printf("%d pages failed in a row, stopping at %s", p.failed, page.Url)
+			p.ended = true
+		}
+		return true
+	}
+	p.failed = 0
+	entries := []string{}
+	for _, m := range page.Mpps {
+		if m.PoPostUrl != nil {
+			entries = append(entries, m.PoPostUrl.String())
+		}
+	}
+	joined := strings.Join(entries, " ")
+	if joined != "" && joined == p.prevEntries {
+		log.Printf("%s repeats the previous page, it is past the last page", page.Url)
+		p.ended = true
+		return false
+	}
+	p.prevEntries = joined
+	if page.Last {
+		log.Printf("%s is the last page", page.Url)
+		p.ended = true
+	}
+	return true
+}
+
+func Run(ctx context.Context, args *Args) (*Result, error) {
+	source, err := SelectSource(args.AlertType)
+	if err != nil {
+		return nil, err
+	}
+	cfg := args.ClientConfig()
+	cfg.CacheMaxAge = func(u *url.URL) time.Duration {
+		if maxAger, ok := source.(ws.CacheMaxAger); ok {
+			if maxAge := maxAger.CacheMaxAge(u); maxAge > 0 {
+				return maxAge
+			}
+		}
+		return args.CacheMaxAge
+	}
+	client := ws.NewClient(cfg)
+	type job struct {
+		seq     uint64
+		pageNum uint64
+		pageUrl string
+	}
+	jobs := make(chan job)
+	done := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		seq, prevUrl := uint64(0), ""
+		for pageNum := args.PageFrom; args.Auto || pageNum <= args.PageUntil; pageNum++ {
+			// Some sources map two page numbers to the same page.
+			pageUrl := source.MakeUrl(pageNum)
+			if pageUrl == prevUrl {
+				continue
+			}
+			prevUrl = pageUrl
+			select {
+			case jobs <- job{seq, pageNum, pageUrl}:
+				seq++
+			case <-done:
+				return
+			}
+		}
+	}()
+	type seqPage struct {
+		seq  uint64
+		page Page
+	}
+	ch := make(chan seqPage)
+	concurrency := args.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				page := Page{Url: j.pageUrl, Mpps: []mpp.MissingPersonPoster{}, Err: ctx.Err()}
+				if ctx.Err() == nil {
+					page = Scrape(ctx, client, j.pageUrl, source)
+				}
+				page.Num = j.pageNum
+				ch <- seqPage{j.seq, page}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	result := Result{Mpps: []mpp.MissingPersonPoster{}}
+	pending := make(map[uint64]Page)
+	next := uint64(0)
+	p := pagination{}
+	stopped := false
+	for r := range ch {
+		pending[r.seq] = r.page
+		for page, ok := pending[next]; ok; page, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if args.Auto && !p.include(page) {
+				continue
+			}
+			result.Pages++
+			result.Mpps = append(result.Mpps, page.Mpps...)
+			if page.Err != nil && ctx.Err() != nil {
+				result.IncompletePages++
+			}
+		}
+		if !stopped && (p.ended || args.Auto && ctx.Err() != nil) {
+			stopped = true
+			close(done)
+		}
+	}
+	return &result, nil
 }
 
 func Execute(args *Args) {
@@ -201,26 +544,30 @@ func Execute(args *Args) {
 		PrintVersion()
 		os.Exit(0)
 	}
-	scraper, makeUrl, err := SelectScraperFuncs(args.AlertType)
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// After the first Ctrl-C the default behavior is restored, so that a
+	// second one kills a run that is slow to drain.
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if args.Timeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(sigCtx, args.Timeout)
+		defer cancel()
+		ctx = timeoutCtx
+	}
+	result, err := Run(ctx, args)
 	if err != nil {
 		log.Fatalf("Error: %s", err)
 	}
-	ch := make(chan []mpp.MissingPersonPoster)
-	for pageNum := args.PageFrom; pageNum <= args.PageUntil; pageNum++ {
-		pageUrl := makeUrl(pageNum)
-		go Scrape(pageUrl, scraper, args.SkipVerify, ch)
-	}
-	mpps := []mpp.MissingPersonPoster{}
-	pagesCount := args.PageUntil - args.PageFrom + 1
-	for curPage := uint64(1); curPage <= pagesCount; curPage++ {
-		mpps = append(mpps, <-ch...)
-	}
-	output, err := json.Marshal(mpps)
+	output, err := json.Marshal(result.Mpps)
 	if err != nil {
 		log.Fatal("Error: ", err)
 	}
 	if args.Out != "" {
-		if os.WriteFile(args.Out, output, 0664) != nil {
+		if err := os.WriteFile(args.Out, output, 0664); err != nil {
 			log.Fatalf("Error: %s", err)
 		}
 	} else {
@@ -229,7 +576,11 @@ func Execute(args *Args) {
 			log.Fatalf("Error: %s", err)
 		}
 	}
-	mppsLen := len(mpps)
+	mppsLen := len(result.Mpps)
 	mppWord := mppLegend(mppsLen)
+	if result.IncompletePages > 0 {
+		log.Printf("%d %s collected; the run is INCOMPLETE, %d of %d pages were interrupted (%s)", mppsLen, mppWord, result.IncompletePages, result.Pages, ctx.Err())
+		os.Exit(ExitIncomplete)
+	}
 	log.Printf("%d %s collected", mppsLen, mppWord)
 }
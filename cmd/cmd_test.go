@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/midir99/rastreadora/mpp"
+	"github.com/midir99/rastreadora/ws"
+)
+
+// seedCassette records the pages of ws/testdata/html under the URLs the
+// sources request, so Run can be tested without reaching the fiscalías.
+func seedCassette(t *testing.T, pages map[string]string) string {
+	t.Helper()
+	cassette := ws.Cassette{Dir: t.TempDir()}
+	for pageUrl, filename := range pages {
+		bodyFile, err := filepath.Abs(filepath.Join("..", "ws", "testdata", "html", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := ws.Exchange{
+			Method:     http.MethodGet,
+			Url:        pageUrl,
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html; charset=UTF-8"}},
+			BodyFile:   bodyFile,
+		}
+		if err := cassette.Save(e, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cassette.Dir
+}
+
+func TestRunReplay(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	testCases := []struct {
+		alertType   AlertType
+		pages       map[string]string
+		wantedMpps  int
+		wantedCheck func(m mpp.MissingPersonPoster) bool
+	}{
+		{
+			"cdmx-custom",
+			map[string]string{
+				"https://personasdesaparecidas.fgjcdmx.gob.mx/listado.php?pa=1&re=100": "cdmx/custom-alerts-page.html",
+			},
+			100,
+			func(m mpp.MissingPersonPoster) bool { return m.PoState == mpp.StateCiudadDeMexico },
+		},
+		{
+			"chis-hasvistoa",
+			map[string]string{
+				"https://www.fge.chiapas.gob.mx/Servicios/Hasvistoa/Page/0":                                         "chis/hva-alerts-page.html",
+				"https://www.fge.chiapas.gob.mx/Servicios/Hasvistoa/HASVISTOA/464843DF-773C-486E-89EF-A3DCBE988A65": "chis/hva-alert-single.html",
+			},
+			12,
+			func(m mpp.MissingPersonPoster) bool { return m.MpHeight == 170 },
+		},
+		{
+			"gro-alba",
+			map[string]string{
+				"https://fiscaliaguerrero.gob.mx/category/alba/page/1/": "gro/alba-alerts-page.html",
+			},
+			10,
+			func(m mpp.MissingPersonPoster) bool { return m.AlertType == mpp.AlertTypeAlba },
+		},
+		{
+			"gro-amber",
+			map[string]string{
+				"https://fiscaliaguerrero.gob.mx/category/amber/page/1/": "gro/amber-alerts-page.html",
+			},
+			10,
+			func(m mpp.MissingPersonPoster) bool { return m.AlertType == mpp.AlertTypeAmber },
+		},
+		{
+			"gro-hasvistoa",
+			map[string]string{
+				"https://fiscaliaguerrero.gob.mx/hasvistoa/?pagina=1": "gro/hva-alerts-page.html",
+			},
+			10,
+			func(m mpp.MissingPersonPoster) bool { return !m.MissingDate.IsZero() },
+		},
+		{
+			"mor-amber",
+			map[string]string{
+				"https://fiscaliamorelos.gob.mx/category/alerta-amber/page/1/":        "mor/amber-alerts-page.html",
+				"https://fiscaliamorelos.gob.mx/2022/06/30/angel-yair-morales-brito/": "mor/amber-alert-single.html",
+			},
+			10,
+			func(m mpp.MissingPersonPoster) bool { return m.PoPosterUrl != nil },
+		},
+		{
+			"mor-custom",
+			map[string]string{
+				"https://fiscaliamorelos.gob.mx/cedulas/1/": "mor/custom-alerts-page.html",
+			},
+			6,
+			func(m mpp.MissingPersonPoster) bool { return m.PoState == mpp.StateMorelos },
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.alertType), func(t *testing.T) {
+			args := &Args{
+				AlertType:   tc.alertType,
+				PageFrom:    1,
+				PageUntil:   1,
+				Concurrency: 1,
+				Replay:      seedCassette(t, tc.pages),
+			}
+			result, err := Run(context.Background(), args)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if len(result.Mpps) != tc.wantedMpps {
+				t.Fatalf("got %d mpps; want %d", len(result.Mpps), tc.wantedMpps)
+			}
+			if result.IncompletePages != 0 {
+				t.Errorf("got %d incomplete pages; want 0", result.IncompletePages)
+			}
+			if !tc.wantedCheck(result.Mpps[0]) {
+				t.Errorf("unexpected first mpp %+v", result.Mpps[0])
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	args := &Args{
+		AlertType:   "gro-alba",
+		PageFrom:    1,
+		PageUntil:   3,
+		Concurrency: 2,
+		Replay:      seedCassette(t, map[string]string{}),
+	}
+	result, err := Run(ctx, args)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if result.IncompletePages != 3 {
+		t.Errorf("got %d incomplete pages; want 3", result.IncompletePages)
+	}
+}
+
+func TestRunAuto(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	testCases := []struct {
+		alertType   AlertType
+		pages       map[string]string
+		wantedMpps  int
+		wantedPages uint64
+	}{
+		{
+			"gro-alba",
+			map[string]string{
+				"https://fiscaliaguerrero.gob.mx/category/alba/page/1/": "gro/alba-alerts-page.html",
+				"https://fiscaliaguerrero.gob.mx/category/alba/page/2/": "gro/alba-alerts-page.html",
+			},
+			10,
+			1,
+		},
+		{
+			"cdmx-custom",
+			map[string]string{
+				"https://personasdesaparecidas.fgjcdmx.gob.mx/listado.php?pa=1&re=100": "cdmx/custom-alerts-page.html",
+				"https://personasdesaparecidas.fgjcdmx.gob.mx/listado.php?pa=2&re=100": "gro/alba-alerts-page.html",
+			},
+			100,
+			2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.alertType), func(t *testing.T) {
+			args := &Args{
+				AlertType:   tc.alertType,
+				PageFrom:    1,
+				Auto:        true,
+				Concurrency: 3,
+				Replay:      seedCassette(t, tc.pages),
+			}
+			result, err := Run(context.Background(), args)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if len(result.Mpps) != tc.wantedMpps {
+				t.Errorf("got %d mpps; want %d", len(result.Mpps), tc.wantedMpps)
+			}
+			if result.Pages != tc.wantedPages {
+				t.Errorf("got %d pages; want %d", result.Pages, tc.wantedPages)
+			}
+		})
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	// The pages are requested through a proxy that holds every tunnel for a
+	// while and then refuses it, so the pages in flight can be counted.
+	var (
+		mu                  sync.Mutex
+		inFlight, maxFlight int
+		requests            int
+	)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		inFlight++
+		if inFlight > maxFlight {
+			maxFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+	proxyUrl, _ := url.Parse(proxy.URL)
+	concurrency := 3
+	args := &Args{
+		AlertType:   "gro-alba",
+		PageFrom:    1,
+		PageUntil:   9,
+		Concurrency: concurrency,
+		Proxy:       proxyUrl,
+	}
+	result, err := Run(context.Background(), args)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if requests != 9 || result.Pages != 9 {
+		t.Errorf("got %d requests for %d pages; want 9 for 9", requests, result.Pages)
+	}
+	if maxFlight > concurrency {
+		t.Errorf("got %d pages in flight; want at most %d", maxFlight, concurrency)
+	}
+	if maxFlight < 2 {
+		t.Errorf("got %d pages in flight; want the pages scraped at the same time", maxFlight)
+	}
+}
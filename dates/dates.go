@@ -0,0 +1,238 @@
+// Package dates parses the dates written in Spanish by the fiscalías, e.g.
+// "15 de marzo de 2022", "marzo 15, 2022", "15-mar-22", "15/03/2022" or
+// "2022-03-15T10:30:00-06:00".
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midir99/rastreadora/internal/fold"
+)
+
+// Precision tells which parts of a Date were written.
+type Precision int
+
+const (
+	// PrecisionYear is a date with only the year, e.g. "2022"; it is the
+	// 1st of January.
+	PrecisionYear Precision = iota + 1
+	// PrecisionMonth is a date without the day, e.g. "marzo de 2022"; it is
+	// the 1st of the month.
+	PrecisionMonth
+	PrecisionDay
+	// PrecisionTime is a date with the time of the day, e.g. an ISO 8601
+	// timestamp.
+	PrecisionTime
+)
+
+func (p Precision) String() string {
+	switch p {
+	case PrecisionYear:
+		return "year"
+	case PrecisionMonth:
+		return "month"
+	case PrecisionDay:
+		return "day"
+	case PrecisionTime:
+		return "time"
+	default:
+		return "unknown"
+	}
+}
+
+// Date is a parsed date and how precise it is. The dates without a time are
+// at midnight.
+type Date struct {
+	time.Time
+	Precision Precision
+}
+
+var months = map[string]time.Month{
+	"enero":      time.January,
+	"ene":        time.January,
+	"febrero":    time.February,
+	"feb":        time.February,
+	"marzo":      time.March,
+	"mar":        time.March,
+	"abril":      time.April,
+	"abr":        time.April,
+	"mayo":       time.May,
+	"may":        time.May,
+	"junio":      time.June,
+	"jun":        time.June,
+	"julio":      time.July,
+	"jul":        time.July,
+	"agosto":     time.August,
+	"ago":        time.August,
+	"septiembre": time.September,
+	"setiembre":  time.September,
+	"sept":       time.September,
+	"sep":        time.September,
+	"set":        time.September,
+	"octubre":    time.October,
+	"oct":        time.October,
+	"noviembre":  time.November,
+	"nov":        time.November,
+	"diciembre":  time.December,
+	"dic":        time.December,
+}
+
+// ignoredWords are the days of the week and the prepositions that go along
+// with the parts of a date. "mar" is not among them, it is March.
+var ignoredWords = map[string]bool{
+	"lunes":     true,
+	"lun":       true,
+	"martes":    true,
+	"miercoles": true,
+	"mie":       true,
+	"jueves":    true,
+	"jue":       true,
+	"viernes":   true,
+	"vie":       true,
+	"sabado":    true,
+	"sab":       true,
+	"domingo":   true,
+	"dom":       true,
+	"de":        true,
+	"del":       true,
+	"el":        true,
+}
+
+// isoLayouts are the layouts of the ISO 8601 dates and timestamps.
+var isoLayouts = []struct {
+	layout    string
+	precision Precision
+}{
+	{time.RFC3339Nano, PrecisionTime},
+	{"2006-01-02T15:04:05", PrecisionTime},
+	{"2006-01-02T15:04", PrecisionTime},
+	{"2006-01-02 15:04:05", PrecisionTime},
+	{"2006-01-02 15:04", PrecisionTime},
+	{"2006-01-02", PrecisionDay},
+}
+
+var (
+	separatorRe = regexp.MustCompile(`[\s,./\-]+`)
+	// ordinalRe matches a day with an ordinal marker, e.g. "1º", "1°",
+	// "1o" or "1ro".
+	ordinalRe = regexp.MustCompile(`^(\d{1,2})(?:º|°|ª|o|ro|er|do|to|vo|no)$`)
+)
+
+// Parse parses a date written in one of the ways used by the fiscalías:
+//
+//   - ISO 8601 dates and timestamps, e.g. "2022-03-15" or
+//     "2022-03-15T10:30:00-06:00";
+//   - day, month and year, e.g. "15 de marzo de 2022", "15-mar-22",
+//     "15/03/2022" or "martes 1º de marzo del 2022";
+//   - month, day and year, e.g. "marzo 15, 2022";
+//   - year, month and day, e.g. "2022/03/15";
+//   - month and year, e.g. "marzo de 2022", or just the year.
+//
+// The months can be written in full or abbreviated, with or without accents
+// and in any case. The years of two digits are taken from 1969 to 2068.
+//
+// The dates are in UTC, see ParseInLocation for the dates of a place.
+func Parse(value string) (Date, error) {
+	return ParseInLocation(value, time.UTC)
+}
+
+// ParseInLocation is like Parse but the dates are in loc, e.g. the zone of the
+// state of the fiscalía. The timestamps with an offset are converted to loc,
+// so that their date is the one seen in that place.
+func ParseInLocation(value string, loc *time.Location) (Date, error) {
+	value = strings.TrimSpace(value)
+	for _, iso := range isoLayouts {
+		if t, err := time.ParseInLocation(iso.layout, value, loc); err == nil {
+			return Date{Time: t.In(loc), Precision: iso.precision}, nil
+		}
+	}
+	var (
+		numbers  []string
+		month    time.Month
+		monthPos = -1
+	)
+	for _, token := range separatorRe.Split(fold.Fold(value), -1) {
+		if token == "" || ignoredWords[token] {
+			continue
+		}
+		if token == "primero" {
+			token = "1"
+		}
+		if m := ordinalRe.FindStringSubmatch(token); m != nil {
+			token = m[1]
+		}
+		if m, ok := months[token]; ok && month == 0 {
+			month, monthPos = m, len(numbers)
+			continue
+		}
+		if _, err := strconv.Atoi(token); err != nil {
+			return Date{}, fmt.Errorf("unable to parse date %s (unknown word: %s)", value, token)
+		}
+		numbers = append(numbers, token)
+	}
+	var day, year string
+	precision := PrecisionDay
+	switch {
+	case month != 0 && len(numbers) == 2:
+		// "15 de marzo de 2022", "marzo 15, 2022" or "2022 marzo 15".
+		day, year = numbers[0], numbers[1]
+		if len(numbers[0]) == 4 {
+			day, year = numbers[1], numbers[0]
+		}
+	case month != 0 && len(numbers) == 1 && monthPos == 0:
+		// "marzo de 2022"
+		year, precision = numbers[0], PrecisionMonth
+	case month == 0 && len(numbers) == 3:
+		// "15/03/2022" or "2022/03/15".
+		var monthNumber string
+		day, monthNumber, year = numbers[0], numbers[1], numbers[2]
+		if len(numbers[0]) == 4 {
+			year, monthNumber, day = numbers[0], numbers[1], numbers[2]
+		}
+		m, _ := strconv.Atoi(monthNumber)
+		if m < 1 || m > 12 {
+			return Date{}, fmt.Errorf("unable to parse date %s (invalid month: %s)", value, monthNumber)
+		}
+		month = time.Month(m)
+	case month == 0 && len(numbers) == 1 && len(numbers[0]) == 4:
+		year, month, precision = numbers[0], time.January, PrecisionYear
+	default:
+		return Date{}, fmt.Errorf("unable to parse date %s", value)
+	}
+	y, err := parseYear(year)
+	if err != nil {
+		return Date{}, fmt.Errorf("unable to parse date %s (invalid year number: %s)", value, year)
+	}
+	d := 1
+	if precision == PrecisionDay {
+		d, _ = strconv.Atoi(day)
+		if d < 1 || d > 31 {
+			return Date{}, fmt.Errorf("unable to parse date %s (invalid day number: %s)", value, day)
+		}
+	}
+	t := time.Date(y, month, d, 0, 0, 0, 0, loc)
+	if t.Day() != d {
+		return Date{}, fmt.Errorf("unable to parse date %s (%s has no day %d)", value, month, d)
+	}
+	return Date{Time: t, Precision: precision}, nil
+}
+
+func parseYear(year string) (int, error) {
+	y, err := strconv.Atoi(year)
+	switch {
+	case err != nil:
+		return 0, err
+	case len(year) == 2 && y >= 69:
+		return 1900 + y, nil
+	case len(year) == 2:
+		return 2000 + y, nil
+	case len(year) == 4:
+		return y, nil
+	default:
+		return 0, fmt.Errorf("invalid year %s", year)
+	}
+}
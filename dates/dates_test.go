@@ -0,0 +1,93 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cst := time.FixedZone("", -6*60*60)
+	testCases := []struct {
+		value           string
+		wanted          time.Time
+		wantedPrecision Precision
+	}{
+		{"15 de marzo de 2022", time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"29 DE JULIO DE 2022", time.Date(2022, time.July, 29, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"marzo 15, 2022", time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"Junio 30, 2022", time.Date(2022, time.June, 30, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"15-mar-22", time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"15-Mar-75", time.Date(1975, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"15/03/2022", time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"02/04/1981", time.Date(1981, time.April, 2, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2022/03/15", time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2022-03-15", time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2022-03-15T10:30:00-06:00", time.Date(2022, time.March, 15, 10, 30, 0, 0, cst), PrecisionTime},
+		{"2022-03-15 10:30:00", time.Date(2022, time.March, 15, 10, 30, 0, 0, time.UTC), PrecisionTime},
+		{"sept. 5, 2021", time.Date(2021, time.September, 5, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"Miércoles 1º de septiembre del 2021", time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"sábado 3ro. de diciembre de 2022", time.Date(2022, time.December, 3, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"primero de enero de 2020", time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"marzo de 2022", time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC), PrecisionMonth},
+		{"2022", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC), PrecisionYear},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			date, err := Parse(tc.value)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if !date.Equal(tc.wanted) {
+				t.Errorf("got %s; want %s", date.Time, tc.wanted)
+			}
+			if date.Precision != tc.wantedPrecision {
+				t.Errorf("got precision %s; want %s", date.Precision, tc.wantedPrecision)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"sin fecha",
+		"15 de marzo",
+		"31 de febrero de 2022",
+		"15/13/2022",
+		"martes 15 de marzo de 2022 a las 10:30",
+	} {
+		t.Run(value, func(t *testing.T) {
+			if date, err := Parse(value); err == nil {
+				t.Errorf("got %s; want an error", date.Time)
+			}
+		})
+	}
+}
+
+func TestParseInLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/Mexico_City")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		value  string
+		wanted string
+	}{
+		// A post published at 21:30 in Mexico City is on the 15th there.
+		{"2022-03-16T03:30:00Z", "2022-03-15T21:30:00-06:00"},
+		{"2022-03-15T10:30:00", "2022-03-15T10:30:00-06:00"},
+		{"15 de marzo de 2022", "2022-03-15T00:00:00-06:00"},
+		{"30/06/2022", "2022-06-30T00:00:00-05:00"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			date, err := ParseInLocation(tc.value, loc)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if got := date.Format(time.RFC3339); got != tc.wanted {
+				t.Errorf("got %s; want %s", got, tc.wanted)
+			}
+		})
+	}
+}
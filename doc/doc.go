@@ -2,51 +2,162 @@ package doc
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/andybalholm/cascadia"
 	"golang.org/x/net/html"
 )
 
+// ErrNoMatch is wrapped by the errors of the strict queries when no element
+// matches the selector.
+var ErrNoMatch = errors.New("no element matches the selector")
+
+// SelectorError is returned by the strict queries when the selector can't be
+// parsed.
+type SelectorError struct {
+	Selector string
+	Err      error
+}
+
+func (e *SelectorError) Error() string {
+	return fmt.Sprintf("invalid selector %q: %s", e.Selector, e.Err)
+}
+
+func (e *SelectorError) Unwrap() error {
+	return e.Err
+}
+
+// selectors caches the compiled selectors (or the parsing errors) by query.
+// The scrapers use a fixed set of queries, so the cache doesn't need to be
+// bounded.
+var selectors sync.Map
+
+func compile(query string) (cascadia.Sel, error) {
+	if cached, ok := selectors.Load(query); ok {
+		if err, ok := cached.(error); ok {
+			return nil, err
+		}
+		return cached.(cascadia.Sel), nil
+	}
+	sel, err := cascadia.Parse(query)
+	if err != nil {
+		err = &SelectorError{Selector: query, Err: err}
+		selectors.Store(query, err)
+		return nil, err
+	}
+	selectors.Store(query, sel)
+	return sel, nil
+}
+
+// Doc wraps an HTML node. Its methods are safe to call on an empty Doc (one
+// whose Node is nil), which is what the queries return when nothing matches.
 type Doc struct {
 	*html.Node
+	// URL is the address the document was fetched from, the queries pass it
+	// on to their results so that relative links can be resolved anywhere.
+	URL *url.URL
+	// base is the URL the relative links are resolved against, it takes the
+	// <base href> of the document into account.
+	base *url.URL
+}
+
+// New returns the Doc of the document node fetched from u, which may be nil
+// if the address is unknown.
+func New(node *html.Node, u *url.URL) *Doc {
+	d := &Doc{Node: node, URL: u}
+	d.base = d.findBase()
+	return d
+}
+
+// wrap returns node as a Doc of the same document as d.
+func (d *Doc) wrap(node *html.Node) *Doc {
+	if d == nil {
+		return &Doc{Node: node}
+	}
+	return &Doc{Node: node, URL: d.URL, base: d.base}
+}
+
+// Found tells whether d holds a node.
+func (d *Doc) Found() bool {
+	return d != nil && d.Node != nil
 }
 
 func (d *Doc) Query(query string) *Doc {
-	sel, err := cascadia.Parse(query)
+	node, _ := d.QueryE(query)
+	return node
+}
+
+// QueryE is the strict version of Query, it returns a *SelectorError if the
+// selector is invalid and an error wrapping ErrNoMatch if nothing matches.
+func (d *Doc) QueryE(query string) (*Doc, error) {
+	sel, err := compile(query)
 	if err != nil {
-		return &Doc{}
+		return d.wrap(nil), err
+	}
+	if !d.Found() {
+		return d.wrap(nil), fmt.Errorf("%q: %w", query, ErrNoMatch)
 	}
 	node := cascadia.Query(d.Node, sel)
 	if node == nil {
-		return &Doc{Node: &html.Node{}}
+		return d.wrap(nil), fmt.Errorf("%q: %w", query, ErrNoMatch)
 	}
-	return &Doc{Node: node}
+	return d.wrap(node), nil
+}
+
+// MustQuery is like QueryE but panics if the selector is invalid or if
+// nothing matches.
+func (d *Doc) MustQuery(query string) *Doc {
+	node, err := d.QueryE(query)
+	if err != nil {
+		panic(err)
+	}
+	return node
 }
 
 func (d *Doc) QueryAll(query string) []*Doc {
-	sel, err := cascadia.Parse(query)
+	docs, _ := d.QueryAllE(query)
+	return docs
+}
+
+// QueryAllE is the strict version of QueryAll, it returns a *SelectorError
+// if the selector is invalid. Matching nothing is not an error.
+func (d *Doc) QueryAllE(query string) ([]*Doc, error) {
+	sel, err := compile(query)
 	if err != nil {
-		return []*Doc{}
+		return []*Doc{}, err
 	}
 	docs := []*Doc{}
+	if !d.Found() {
+		return docs, nil
+	}
 	for _, node := range cascadia.QueryAll(d.Node, sel) {
-		docs = append(docs, &Doc{Node: node})
+		docs = append(docs, d.wrap(node))
 	}
-	return docs
+	return docs, nil
 }
 
 func (d *Doc) NthChild(n int) *Doc {
+	if !d.Found() {
+		return d.wrap(nil)
+	}
 	p := 0
 	for child := d.Node.FirstChild; child != nil; child = child.NextSibling {
 		if p == n {
-			return &Doc{Node: child}
+			return d.wrap(child)
 		}
 		p++
 	}
-	return &Doc{}
+	return d.wrap(nil)
 }
 
 func (d *Doc) Text() string {
+	if !d.Found() {
+		return ""
+	}
 	var buf bytes.Buffer
 	var f func(node *html.Node)
 	f = func(node *html.Node) {
@@ -62,10 +173,89 @@ func (d *Doc) Text() string {
 }
 
 func (d *Doc) AttrOr(attr, or string) string {
+	if value, ok := d.attr(attr); ok {
+		return value
+	}
+	return or
+}
+
+func (d *Doc) attr(attr string) (string, bool) {
+	if !d.Found() {
+		return "", false
+	}
 	for _, a := range d.Node.Attr {
 		if a.Key == attr {
-			return a.Val
+			return a.Val, true
 		}
 	}
-	return or
+	return "", false
+}
+
+// Base returns the URL the relative links of the document are resolved
+// against: the <base href> of the document resolved against URL, or URL
+// itself if there is no <base>. It is nil if neither is known.
+func (d *Doc) Base() *url.URL {
+	if d == nil {
+		return nil
+	}
+	if d.base != nil {
+		return d.base
+	}
+	return d.findBase()
+}
+
+func (d *Doc) findBase() *url.URL {
+	if !d.Found() {
+		return d.URL
+	}
+	root := d.Node
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	href := strings.TrimSpace((&Doc{Node: root}).Query("base[href]").AttrOr("href", ""))
+	if href == "" {
+		return d.URL
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return d.URL
+	}
+	if d.URL == nil {
+		if !ref.IsAbs() {
+			return nil
+		}
+		return ref
+	}
+	return d.URL.ResolveReference(ref)
+}
+
+// ResolveURL parses ref and resolves it against the base URL of the document.
+// It fails if ref is empty or if it is relative and the base URL is unknown.
+func (d *Doc) ResolveURL(ref string) (*url.URL, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("the URL is empty")
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	base := d.Base()
+	if base == nil {
+		if !u.IsAbs() {
+			return nil, fmt.Errorf("can't resolve %q without a base URL", ref)
+		}
+		return u, nil
+	}
+	return base.ResolveReference(u), nil
+}
+
+// AbsURL returns the value of the attribute attr (e.g. "href" or "src")
+// resolved against the base URL of the document.
+func (d *Doc) AbsURL(attr string) (*url.URL, error) {
+	value, ok := d.attr(attr)
+	if !ok {
+		return nil, fmt.Errorf("the %s attribute is missing", attr)
+	}
+	return d.ResolveURL(value)
 }
@@ -0,0 +1,439 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+func parse(t *testing.T, s string) *Doc {
+	t.Helper()
+	node, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Doc{Node: node}
+}
+
+func TestQueryE(t *testing.T) {
+	d := parse(t, `<article><h2><a href="/post">Martina Bello Morales</a></h2></article>`)
+	testCases := []struct {
+		query      string
+		wantedText string
+		wantedErr  func(error) bool
+	}{
+		{"h2 a", "Martina Bello Morales", func(err error) bool { return err == nil }},
+		{"h3 a", "", func(err error) bool { return errors.Is(err, ErrNoMatch) }},
+		{"h2 >", "", func(err error) bool {
+			var selErr *SelectorError
+			return errors.As(err, &selErr)
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			node, err := d.QueryE(tc.query)
+			if !tc.wantedErr(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+			if got := node.Text(); got != tc.wantedText {
+				t.Errorf("got %q; want %q", got, tc.wantedText)
+			}
+		})
+	}
+}
+
+func TestEmptyDocIsSafe(t *testing.T) {
+	d := parse(t, `<p>text</p>`)
+	missing := d.Query("h2").Query("a").NthChild(3)
+	if missing.Found() {
+		t.Errorf("got a node; want an empty Doc")
+	}
+	if got := missing.Text(); got != "" {
+		t.Errorf("got %q; want empty text", got)
+	}
+	if got := missing.AttrOr("href", "none"); got != "none" {
+		t.Errorf("got %q; want none", got)
+	}
+	if got := len(missing.QueryAll("a")); got != 0 {
+		t.Errorf("got %d nodes; want 0", got)
+	}
+	if got := (&Doc{}).Query("[").Text(); got != "" {
+		t.Errorf("got %q; want empty text", got)
+	}
+}
+
+func TestXPathE(t *testing.T) {
+	d := parse(t, `<figure><a href="/backup/poster.pdf"><img src="/poster.jpg"></a><figcaption><h4>Martina Bello<br>2022-05-31</h4></figcaption></figure>`)
+	figure := d.Query("figure")
+	testCases := []struct {
+		expr       string
+		wantedText string
+		wantedErr  func(error) bool
+	}{
+		{".//h4/text()[1]", "Martina Bello", func(err error) bool { return err == nil }},
+		{".//h4/br/following-sibling::text()[1]", "2022-05-31", func(err error) bool { return err == nil }},
+		{"a/@href", "/backup/poster.pdf", func(err error) bool { return err == nil }},
+		{"a/img/@alt", "", func(err error) bool { return errors.Is(err, ErrNoMatch) }},
+		{"a[", "", func(err error) bool {
+			var exprErr *ExprError
+			return errors.As(err, &exprErr)
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			node, err := figure.XPathE(tc.expr)
+			if !tc.wantedErr(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+			if got := node.Text(); got != tc.wantedText {
+				t.Errorf("got %q; want %q", got, tc.wantedText)
+			}
+		})
+	}
+	if got := len(d.XPathAll("//h4/text()")); got != 2 {
+		t.Errorf("got %d text nodes; want 2", got)
+	}
+	if got := len((&Doc{}).XPathAll("//h4")); got != 0 {
+		t.Errorf("got %d nodes; want 0", got)
+	}
+}
+
+func TestAbsURL(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(`<a id="rel" href="PDF/consulta.php?id=1"></a><a id="up" href="../fotos/a.jpg"></a><a id="root" href="/backup/a.pdf"></a><a id="proto" href="//cdn.example.com/a.jpg"></a><a id="abs" href="https://example.org/post/"></a><a id="empty" href=""></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := url.Parse("https://example.com/listado/index.php?pa=2")
+	withBase, err := html.Parse(strings.NewReader(`<head><base href="https://static.example.com/v1/"></head><a id="rel" href="a.jpg"></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name   string
+		d      *Doc
+		id     string
+		wanted string
+	}{
+		{"relative", New(node, page), "rel", "https://example.com/listado/PDF/consulta.php?id=1"},
+		{"parent directory", New(node, page), "up", "https://example.com/fotos/a.jpg"},
+		{"absolute path", New(node, page), "root", "https://example.com/backup/a.pdf"},
+		{"protocol relative", New(node, page), "proto", "https://cdn.example.com/a.jpg"},
+		{"absolute", New(node, page), "abs", "https://example.org/post/"},
+		{"absolute without base", New(node, nil), "abs", "https://example.org/post/"},
+		{"base element", New(withBase, page), "rel", "https://static.example.com/v1/a.jpg"},
+		{"relative without base", New(node, nil), "rel", ""},
+		{"empty", New(node, page), "empty", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := tc.d.Query("#" + tc.id).AbsURL("href")
+			if tc.wanted == "" {
+				if err == nil {
+					t.Errorf("got %s; want an error", u)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %s", err)
+			}
+			if got := u.String(); got != tc.wanted {
+				t.Errorf("got %s; want %s", got, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestTextWith(t *testing.T) {
+	d := parse(t, "<div id=\"data\"><script>var x = 1;</script><p>Se extravi\u00F3 el\u00A0d\u00EDa  29\n de julio,</p><p>\u200Bvest\u00EDa una  playera<br>roja.</p><style>p { color: red }</style></div>").Query("#data")
+	testCases := []struct {
+		name   string
+		opts   TextOptions
+		wanted string
+	}{
+		{"raw", TextOptions{}, "var x = 1;Se extravi\u00F3 el\u00A0d\u00EDa  29\n de julio,\u200Bvest\u00EDa una  playeraroja.p { color: red }"},
+		{"collapse", TextOptions{Collapse: true, SkipNonContent: true}, "Se extravi\u00F3 el d\u00EDa 29 de julio,\u200Bvest\u00EDa una playeraroja."},
+		{"blocks", TextOptions{Blocks: true, SkipNonContent: true}, "\n\nSe extravi\u00F3 el\u00A0d\u00EDa  29\n de julio,\n\n\u200Bvest\u00EDa una  playera\n\nroja.\n\n"},
+		{"clean", CleanTextOptions, "Se extravi\u00F3 el d\u00EDa 29 de julio,\nvest\u00EDa una playera\nroja."},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := d.TextWith(tc.opts); got != tc.wanted {
+				t.Errorf("got %q; want %q", got, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	d := parse(t, `<div class="row"><div><label>Sexo:</label></div><div><p>HOMBRE</p></div></div>
+<div class="row"><div><label>Fecha desaparici&oacute;n:</label></div><div><p>04/03/2006</p></div></div>
+<div class="row"><div><label>Tama&ntilde;o de nariz</label></div><div><p>MEDIANA</p></div></div>
+<p>Registro : <span>228/2013</span></p>
+<b>Fecha de nacimiento:</b>
+<p>02/04/1981</p>
+<dl><dt>Ojos</dt><dd>CAF&Eacute;S</dd></dl>
+<table><tr><th>Peso</th><td>68kg.</td></tr></table>
+<td>KAROL ANELIT<br>Edad:&nbsp;15 A&ntilde;os<br>Se Extravi&oacute; el:&nbsp;29 de Julio de 2022<br></td>
+<p>Una oraci&oacute;n larga que no es una etiqueta aunque termine en dos puntos: y siga</p>`)
+	fields := d.Fields()
+	testCases := []struct {
+		label  string
+		wanted string
+	}{
+		{"sexo", "HOMBRE"},
+		{"FECHA DESAPARICION", "04/03/2006"},
+		{"Tama\u00F1o de nariz:", "MEDIANA"},
+		{"Registro", "228/2013"},
+		{"Fecha de nacimiento", "02/04/1981"},
+		{"Ojos", "CAF\u00C9S"},
+		{"Peso", "68kg."},
+		{"Edad", "15 A\u00F1os"},
+		{"Se extravi\u00F3 el", "29 de Julio de 2022"},
+		{"Estatura", ""},
+		{"Una oraci\u00F3n larga que no es una etiqueta aunque termine en dos puntos", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			if got := fields.Text(tc.label); got != tc.wanted {
+				t.Errorf("got %q; want %q", got, tc.wanted)
+			}
+		})
+	}
+	if got := fields.Text("Estatura", "Peso"); got != "68kg." {
+		t.Errorf("got %q; want %q", got, "68kg.")
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	RegisterParser("test-upper", func(value string) (interface{}, error) {
+		return strings.ToUpper(value), nil
+	})
+	t.Cleanup(func() { unregisterParser("test-upper") })
+	node, err := html.Parse(strings.NewReader(`<article>
+<h2><a href="/2022/06/30/post/">Martina Bello</a></h2>
+<time class="entry-date" datetime="2022-06-30T10:00:00-05:00"></time>
+<img src="foto-480x320.jpg">
+<p>Edad: 15</p>
+</article>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := url.Parse("https://example.com/category/alba/")
+	d := New(node, page).Query("article")
+	entry := struct {
+		Title     string    `css:"h2 a" required:"true"`
+		Upper     string    `css:"h2 a" parse:"test-upper"`
+		PostUrl   *url.URL  `css:"h2 a" attr:"href" abs:"true" required:"true"`
+		Date      time.Time `css:".entry-date.published; .entry-date" attr:"datetime"`
+		Poster    string    `css:"img" attr:"src" abs:"true"`
+		Age       int       `label:"Edad"`
+		Heading   *Doc      `css:"h2"`
+		Missing   string    `css:".missing"`
+		Untouched string
+	}{Untouched: "keep"}
+	if err := Unmarshal(d, &entry); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	testCases := []struct {
+		field  string
+		got    string
+		wanted string
+	}{
+		{"Title", entry.Title, "Martina Bello"},
+		{"Upper", entry.Upper, "MARTINA BELLO"},
+		{"PostUrl", entry.PostUrl.String(), "https://example.com/2022/06/30/post/"},
+		{"Date", entry.Date.UTC().Format(time.RFC3339), "2022-06-30T15:00:00Z"},
+		{"Poster", entry.Poster, "https://example.com/category/alba/foto-480x320.jpg"},
+		{"Age", strconv.Itoa(entry.Age), "15"},
+		{"Heading", entry.Heading.Data, "h2"},
+		{"Missing", entry.Missing, ""},
+		{"Untouched", entry.Untouched, "keep"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.field, func(t *testing.T) {
+			if tc.got != tc.wanted {
+				t.Errorf("got %q; want %q", tc.got, tc.wanted)
+			}
+		})
+	}
+
+	invalid := struct {
+		Name  string `css:".missing" required:"true"`
+		Age   int    `css:"h2 a"`
+		Other string `css:"h2 a" parse:"unknown"`
+	}{}
+	err = Unmarshal(d, &invalid)
+	var fieldErrs FieldErrors
+	if !errors.As(err, &fieldErrs) || len(fieldErrs) != 3 {
+		t.Fatalf("got error %v; want 3 field errors", err)
+	}
+	if !errors.Is(fieldErrs.Required(), ErrNoMatch) {
+		t.Errorf("got required errors %v; want %s", fieldErrs.Required(), ErrNoMatch)
+	}
+	if err := Unmarshal(d, invalid); err == nil {
+		t.Errorf("got nil error for a non-pointer")
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	testCases := []struct {
+		name            string
+		head            string
+		wantedImage     string
+		wantedCanonical string
+		wantedPublished string
+	}{
+		{
+			"json-ld graph",
+			`<script type="application/ld+json">{"@context":"https://schema.org","@graph":[{"@type":"Article","datePublished":"2022-06-30T21:55:37+00:00","image":{"@id":"/post/#primaryimage"}},{"@type":"ImageObject","@id":"/post/#primaryimage","url":"/uploads/ld.jpg"}]}</script><meta property="og:image" content="/uploads/og.jpg">`,
+			"https://example.com/uploads/ld.jpg", "", "2022-06-30T21:55:37Z",
+		},
+		{
+			"opengraph",
+			`<meta property="og:image" content="https://example.com/uploads/og.jpg"><meta property="og:url" content="https://example.com/post/?p=1"><meta property="article:published_time" content="2022-06-30T16:55:37-05:00"><link rel="canonical" href="/post/">`,
+			"https://example.com/uploads/og.jpg", "https://example.com/post/", "2022-06-30T21:55:37Z",
+		},
+		{
+			"twitter",
+			`<meta name="twitter:image" content="/uploads/tw.jpg"><script type="application/ld+json">{ invalid</script>`,
+			"https://example.com/uploads/tw.jpg", "", "0001-01-01T00:00:00Z",
+		},
+	}
+	page, _ := url.Parse("https://example.com/post/")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := html.Parse(strings.NewReader("<html><head>" + tc.head + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := New(node, page).Metadata()
+			if got := m.Image.String(); got != tc.wantedImage {
+				t.Errorf("got image %s; want %s", got, tc.wantedImage)
+			}
+			var canonical string
+			if m.Canonical != nil {
+				canonical = m.Canonical.String()
+			}
+			if canonical != tc.wantedCanonical {
+				t.Errorf("got canonical %s; want %s", canonical, tc.wantedCanonical)
+			}
+			if got := m.Published.UTC().Format(time.RFC3339); got != tc.wantedPublished {
+				t.Errorf("got published %s; want %s", got, tc.wantedPublished)
+			}
+		})
+	}
+}
+
+func TestTable(t *testing.T) {
+	d := parse(t, `<table>
+<thead>
+<tr><th rowspan="2">Nombre</th><th colspan="2">Desaparici&oacute;n</th><th rowspan="2">Foto</th></tr>
+<tr><th>Fecha</th><th>Lugar</th></tr>
+</thead>
+<tbody>
+<tr><td>Martina Bello</td><td>2022-05-31</td><td rowspan="2">Chilpancingo</td><td><img src="/fotos/1.jpg"></td></tr>
+<tr><td>Karol Guzm&aacute;n</td><td>2022-07-29</td><td><a href="/fotos/2.jpg">ver</a></td></tr>
+</tbody>
+<tbody>
+<tr><td colspan="2">Luis Solis</td><td>Iguala</td></tr>
+</tbody>
+<tfoot><tr><td colspan="4">P&aacute;gina 1 de 390</td></tr></tfoot>
+</table>`)
+	rows := d.Table()
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows; want 3", len(rows))
+	}
+	testCases := []struct {
+		row    int
+		header string
+		wanted string
+	}{
+		{0, "Nombre", "Martina Bello"},
+		{0, "Desaparicion Fecha", "2022-05-31"},
+		{0, "desaparici\u00F3n lugar", "Chilpancingo"},
+		{1, "Nombre", "Karol Guzm\u00E1n"},
+		{1, "Desaparicion Lugar", "Chilpancingo"},
+		{1, "Foto", "ver"},
+		{2, "Nombre", "Luis Solis"},
+		{2, "Desaparicion Fecha", "Luis Solis"},
+		{2, "Desaparicion Lugar", "Iguala"},
+		{2, "Foto", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%d %s", tc.row, tc.header), func(t *testing.T) {
+			if got := rows[tc.row].Text(tc.header); got != tc.wanted {
+				t.Errorf("got %q; want %q", got, tc.wanted)
+			}
+		})
+	}
+	if got := rows[0].Get("Foto").Query("img").AttrOr("src", ""); got != "/fotos/1.jpg" {
+		t.Errorf("got %q; want /fotos/1.jpg", got)
+	}
+
+	rows = parse(t, `<table><tr><td>a</td><td>b</td></tr></table>`).Table()
+	if len(rows) != 1 || rows[0].Text("1") != "a" || rows[0].Text("2") != "b" {
+		t.Errorf("got %v; want the columns named by position", rows)
+	}
+}
+
+func loadFixture(b *testing.B, filename string) *Doc {
+	b.Helper()
+	file, err := os.Open(filepath.Join("..", "ws", "testdata", "html", filename))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	node, err := html.Parse(file)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &Doc{Node: node}
+}
+
+// queryCdmxPage runs the queries made by the CDMX scraper on a page of 100
+// rows.
+func queryCdmxPage(d *Doc, queryAll func(d *Doc, query string) []*Doc) {
+	for _, tr := range queryAll(d, "tbody tr") {
+		for _, td := range queryAll(tr, "td") {
+			queryAll(td, "img")
+		}
+	}
+}
+
+func BenchmarkQueryAllCdmx(b *testing.B) {
+	d := loadFixture(b, "cdmx/custom-alerts-page.html")
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			queryCdmxPage(d, (*Doc).QueryAll)
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			queryCdmxPage(d, func(d *Doc, query string) []*Doc {
+				sel, _ := cascadia.Parse(query)
+				docs := []*Doc{}
+				for _, node := range cascadia.QueryAll(d.Node, sel) {
+					docs = append(docs, &Doc{Node: node})
+				}
+				return docs
+			})
+		}
+	})
+}
+
+// unregisterParser removes a parser registered by a test, so that the test
+// can run again in the same process.
+func unregisterParser(name string) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	delete(parsers, name)
+}
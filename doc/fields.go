@@ -0,0 +1,149 @@
+package doc
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/midir99/rastreadora/internal/fold"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxLabelLength keeps the sentences that happen to have a colon from being
+// taken as labels.
+const maxLabelLength = 40
+
+// labelElements are the elements whose text is a label when the value is
+// not inside them, e.g. <label>Sexo:</label> or <dt>Edad</dt>.
+var labelElements = map[atom.Atom]bool{
+	atom.B:      true,
+	atom.Dt:     true,
+	atom.Label:  true,
+	atom.Strong: true,
+	atom.Th:     true,
+}
+
+// Fields maps the normalized labels of a page to the nodes that hold their
+// values. Use Get or Text to look a label up.
+type Fields map[string]*Doc
+
+// NormalizeLabel returns the key of label in Fields: lower case, without
+// accents, trailing colon or repeated spaces.
+func NormalizeLabel(label string) string {
+	label = strings.TrimRight(spaceReplacer.Replace(label), ": \t\r\n")
+	return fold.Fold(strings.Join(strings.Fields(label), " "))
+}
+
+// Fields collects the label/value pairs found in d. It understands:
+//
+//   - definition lists (<dt>Label</dt><dd>value</dd>) and table headers
+//     (<th>Label</th><td>value</td>);
+//   - label elements (<label>, <b>, <strong>) followed by the value, either
+//     as their next sibling or, if the label is alone in its parent, as the
+//     next sibling of the parent;
+//   - "Label: value" text, or a "Label:" text followed by an element.
+//
+// When a label appears more than once the first value wins.
+func (d *Doc) Fields() Fields {
+	fields := Fields{}
+	if !d.Found() {
+		return fields
+	}
+	add := func(label string, value *html.Node) {
+		key := NormalizeLabel(label)
+		if key == "" || utf8.RuneCountInString(key) > maxLabelLength {
+			return
+		}
+		if _, ok := fields[key]; !ok {
+			fields[key] = d.wrap(value)
+		}
+	}
+	var f func(node *html.Node)
+	f = func(node *html.Node) {
+		switch {
+		case node.Type == html.ElementNode && labelElements[node.DataAtom]:
+			text := (&Doc{Node: node}).CleanText()
+			if label, value, ok := strings.Cut(text, ":"); !ok || strings.TrimSpace(value) == "" {
+				if value := valueAfter(node); value != nil {
+					add(label, value)
+				}
+			}
+		case node.Type == html.TextNode:
+			label, value, ok := strings.Cut(spaceReplacer.Replace(node.Data), ":")
+			if !ok {
+				break
+			}
+			if strings.TrimSpace(value) != "" {
+				add(label, &html.Node{Type: html.TextNode, Data: value})
+			} else if next := nextContent(node); next != nil && next.Type == html.ElementNode {
+				add(label, next)
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			f(child)
+		}
+	}
+	f(d.Node)
+	return fields
+}
+
+// Get returns the value of the first of labels found, or an empty Doc. The
+// labels are matched regardless of case, accents and trailing colon.
+func (f Fields) Get(labels ...string) *Doc {
+	for _, label := range labels {
+		if value, ok := f[NormalizeLabel(label)]; ok {
+			return value
+		}
+	}
+	return &Doc{}
+}
+
+// Text returns the clean text of the value of the first of labels found.
+func (f Fields) Text(labels ...string) string {
+	return f.Get(labels...).CleanText()
+}
+
+// valueAfter returns the node holding the value of a label element: the
+// next sibling with content or, while the label is the only content of its
+// parent, the next sibling of the parent.
+func valueAfter(node *html.Node) *html.Node {
+	for depth := 0; node != nil && node.Type == html.ElementNode && depth < 3; depth++ {
+		if next := nextContent(node); next != nil {
+			return next
+		}
+		if prevContent(node) != nil {
+			return nil
+		}
+		node = node.Parent
+	}
+	return nil
+}
+
+func hasContent(node *html.Node) bool {
+	switch node.Type {
+	case html.ElementNode:
+		return node.DataAtom != atom.Br && node.DataAtom != atom.Hr
+	case html.TextNode:
+		return strings.TrimSpace(spaceReplacer.Replace(node.Data)) != ""
+	default:
+		return false
+	}
+}
+
+func nextContent(node *html.Node) *html.Node {
+	for next := node.NextSibling; next != nil; next = next.NextSibling {
+		if hasContent(next) {
+			return next
+		}
+	}
+	return nil
+}
+
+func prevContent(node *html.Node) *html.Node {
+	for prev := node.PrevSibling; prev != nil; prev = prev.PrevSibling {
+		if hasContent(prev) {
+			return prev
+		}
+	}
+	return nil
+}
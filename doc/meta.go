@@ -0,0 +1,185 @@
+package doc
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Meta returns the content of the first <meta> whose name or property is key
+// (e.g. "description", "og:image" or "article:published_time"), the key is
+// case insensitive. It is empty if there is no such tag.
+func (d *Doc) Meta(key string) string {
+	for _, meta := range d.QueryAll("meta[content]") {
+		name := meta.AttrOr("property", meta.AttrOr("name", ""))
+		if strings.EqualFold(strings.TrimSpace(name), key) {
+			return strings.TrimSpace(meta.AttrOr("content", ""))
+		}
+	}
+	return ""
+}
+
+// OpenGraph returns the OpenGraph properties of the document (og:*, article:*
+// and the like) by property, the first value of a repeated property wins.
+func (d *Doc) OpenGraph() map[string]string {
+	properties := make(map[string]string)
+	for _, meta := range d.QueryAll("meta[property][content]") {
+		property := strings.ToLower(strings.TrimSpace(meta.AttrOr("property", "")))
+		if _, ok := properties[property]; !ok && property != "" {
+			properties[property] = strings.TrimSpace(meta.AttrOr("content", ""))
+		}
+	}
+	return properties
+}
+
+// JSONLD returns the objects of the JSON-LD blocks of the document. The
+// arrays and the @graph of the blocks are flattened, so every object stands
+// on its own; the invalid blocks are skipped.
+func (d *Doc) JSONLD() []map[string]interface{} {
+	objects := []map[string]interface{}{}
+	var add func(v interface{})
+	add = func(v interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				add(item)
+			}
+		case map[string]interface{}:
+			if graph, ok := v["@graph"]; ok {
+				add(graph)
+				return
+			}
+			objects = append(objects, v)
+		}
+	}
+	for _, script := range d.QueryAll(`script[type="application/ld+json"]`) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(script.Text()), &v); err == nil {
+			add(v)
+		}
+	}
+	return objects
+}
+
+// Metadata is the data about a page published for search engines and social
+// networks.
+type Metadata struct {
+	Title       string
+	Description string
+	// Image is the main image of the page, e.g. the photo of a post.
+	Image *url.URL
+	// Canonical is the preferred URL of the page.
+	Canonical *url.URL
+	Published time.Time
+	Modified  time.Time
+}
+
+// articleTypes are the JSON-LD types that describe the page itself.
+var articleTypes = []string{"Article", "NewsArticle", "BlogPosting", "Report", "WebPage"}
+
+// Metadata collects the metadata of the document. Every value is taken from
+// the first of these that has it: the JSON-LD article (or web page), the
+// OpenGraph properties, the standard and Twitter meta tags and finally the
+// <title>. The canonical URL prefers the <link rel="canonical"> though.
+func (d *Doc) Metadata() Metadata {
+	m := Metadata{}
+	objects := d.JSONLD()
+	byId := make(map[string]map[string]interface{})
+	for _, object := range objects {
+		if id, ok := object["@id"].(string); ok {
+			byId[id] = object
+		}
+	}
+	article := map[string]interface{}{}
+	for _, typ := range articleTypes {
+		if object := findJSONLDType(objects, typ); object != nil {
+			article = object
+			break
+		}
+	}
+	og := d.OpenGraph()
+	m.Title = firstNonEmpty(jsonLDString(article["headline"]), og["og:title"], d.Meta("twitter:title"), d.Query("title").CleanText())
+	m.Description = firstNonEmpty(jsonLDString(article["description"]), og["og:description"], d.Meta("description"))
+	m.Image = d.firstURL(jsonLDURL(article["image"], byId), og["og:image:secure_url"], og["og:image"], d.Meta("twitter:image"))
+	m.Canonical = d.firstURL(d.Query(`link[rel="canonical"]`).AttrOr("href", ""), og["og:url"], jsonLDURL(article["url"], byId))
+	m.Published = firstTime(jsonLDString(article["datePublished"]), og["article:published_time"], d.Meta("date"))
+	m.Modified = firstTime(jsonLDString(article["dateModified"]), og["article:modified_time"], og["og:updated_time"])
+	return m
+}
+
+func findJSONLDType(objects []map[string]interface{}, typ string) map[string]interface{} {
+	for _, object := range objects {
+		switch t := object["@type"].(type) {
+		case string:
+			if strings.EqualFold(t, typ) {
+				return object
+			}
+		case []interface{}:
+			for _, item := range t {
+				if s, ok := item.(string); ok && strings.EqualFold(s, typ) {
+					return object
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func jsonLDString(v interface{}) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// jsonLDURL returns the URL held by v, which can be the URL itself, an
+// object with a url or contentUrl (or a reference to one by @id) or an
+// array of those.
+func jsonLDURL(v interface{}, byId map[string]map[string]interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		for _, item := range v {
+			if s := jsonLDURL(item, byId); s != "" {
+				return s
+			}
+		}
+	case map[string]interface{}:
+		if s := firstNonEmpty(jsonLDString(v["url"]), jsonLDString(v["contentUrl"])); s != "" {
+			return s
+		}
+		if id, ok := v["@id"].(string); ok {
+			if object, ok := byId[id]; ok {
+				return firstNonEmpty(jsonLDString(object["url"]), jsonLDString(object["contentUrl"]))
+			}
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (d *Doc) firstURL(values ...string) *url.URL {
+	for _, value := range values {
+		if u, err := d.ResolveURL(value); err == nil {
+			return u
+		}
+	}
+	return nil
+}
+
+func firstTime(values ...string) time.Time {
+	for _, value := range values {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,183 @@
+package doc
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxColspan and maxRowspan cap the spans like the browsers do, so that a
+// broken page can't make Table allocate a huge grid.
+const (
+	maxColspan = 1000
+	maxRowspan = 65534
+)
+
+// Table returns the rows of the table d (or of the first table inside d) as
+// maps from the header of each column to the cell in that column, see Fields
+// for how to look a header up. The cells keep their links and images, e.g.
+// row.Get("Foto").Query("img").AbsURL("src").
+//
+// The header is made of the rows of the <thead> or, without one, of the
+// leading rows that only have <th> cells; a column under several header rows
+// is named after all of them, joined by spaces. The columns without a header
+// are named by their position, starting at "1". A cell that spans several
+// columns or rows appears in every one of them. The rows of every <tbody>
+// are returned but not the ones of the <tfoot>, which usually holds totals
+// or the pagination; nested tables are left inside their cells.
+func (d *Doc) Table() []Fields {
+	rows := []Fields{}
+	table := d
+	if !d.Found() || d.DataAtom != atom.Table {
+		table = d.Query("table")
+	}
+	if !table.Found() {
+		return rows
+	}
+	var head, body []*html.Node
+	for child := table.FirstChild; child != nil; child = child.NextSibling {
+		switch child.DataAtom {
+		case atom.Thead:
+			head = append(head, rowsOf(child)...)
+		case atom.Tbody:
+			body = append(body, rowsOf(child)...)
+		case atom.Tr:
+			body = append(body, child)
+		}
+	}
+	if len(head) == 0 {
+		for len(body) > 0 && isHeaderRow(body[0]) {
+			head, body = append(head, body[0]), body[1:]
+		}
+	}
+	grid := layoutRows(append(head, body...))
+	headerGrid, bodyGrid := grid[:len(head)], grid[len(head):]
+	width := 0
+	for _, cells := range grid {
+		if len(cells) > width {
+			width = len(cells)
+		}
+	}
+	headers := make([]string, width)
+	for col := range headers {
+		labels := []string{}
+		var prev *html.Node
+		for _, cells := range headerGrid {
+			if col >= len(cells) || cells[col] == nil || cells[col] == prev {
+				continue
+			}
+			prev = cells[col]
+			if label := (&Doc{Node: cells[col]}).CleanText(); label != "" {
+				labels = append(labels, label)
+			}
+		}
+		headers[col] = NormalizeLabel(strings.Join(labels, " "))
+		if headers[col] == "" {
+			headers[col] = strconv.Itoa(col + 1)
+		}
+	}
+	for _, cells := range bodyGrid {
+		row := Fields{}
+		for col, cell := range cells {
+			if _, ok := row[headers[col]]; cell != nil && !ok {
+				row[headers[col]] = table.wrap(cell)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func rowsOf(section *html.Node) []*html.Node {
+	rows := []*html.Node{}
+	for child := section.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Tr {
+			rows = append(rows, child)
+		}
+	}
+	return rows
+}
+
+func cellsOf(row *html.Node) []*html.Node {
+	cells := []*html.Node{}
+	for child := row.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Td || child.DataAtom == atom.Th {
+			cells = append(cells, child)
+		}
+	}
+	return cells
+}
+
+func isHeaderRow(row *html.Node) bool {
+	cells := cellsOf(row)
+	for _, cell := range cells {
+		if cell.DataAtom != atom.Th {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+func span(cell *html.Node, attr string, max int) int {
+	n, err := strconv.Atoi(strings.TrimSpace((&Doc{Node: cell}).AttrOr(attr, "1")))
+	if err != nil || n < 1 {
+		return 1
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// layoutRows places the cells of rows in a grid, repeating the cells that
+// span several columns or rows.
+func layoutRows(rows []*html.Node) [][]*html.Node {
+	type spanning struct {
+		cell *html.Node
+		left int
+	}
+	grid := make([][]*html.Node, len(rows))
+	carry := map[int]*spanning{}
+	for r, row := range rows {
+		above, next := carry, map[int]*spanning{}
+		cells := []*html.Node{}
+		place := func() bool {
+			s, ok := above[len(cells)]
+			if !ok {
+				return false
+			}
+			if s.left > 1 {
+				next[len(cells)] = &spanning{cell: s.cell, left: s.left - 1}
+			}
+			cells = append(cells, s.cell)
+			return true
+		}
+		for _, cell := range cellsOf(row) {
+			for place() {
+			}
+			colspan, rowspan := span(cell, "colspan", maxColspan), span(cell, "rowspan", maxRowspan)
+			for i := 0; i < colspan; i++ {
+				if rowspan > 1 {
+					next[len(cells)] = &spanning{cell: cell, left: rowspan - 1}
+				}
+				cells = append(cells, cell)
+			}
+		}
+		last := -1
+		for col := range above {
+			if col > last {
+				last = col
+			}
+		}
+		for len(cells) <= last {
+			if !place() {
+				cells = append(cells, nil)
+			}
+		}
+		grid[r] = cells
+		carry = next
+	}
+	return grid
+}
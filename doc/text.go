@@ -0,0 +1,130 @@
+package doc
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TextOptions tells TextWith how to clean up the text of a node.
+type TextOptions struct {
+	// Collapse replaces every run of white space (line breaks included) with
+	// a single space and trims the text, or every line of it if Blocks is
+	// set (the empty lines are dropped).
+	Collapse bool
+	// Normalize turns the non-breaking and zero-width spaces (e.g. the
+	// &nbsp; entity) into plain spaces.
+	Normalize bool
+	// SkipNonContent leaves out the text of the elements that are not
+	// displayed, such as <script>, <style> and <template>.
+	SkipNonContent bool
+	// Blocks keeps the boundaries of the block elements (paragraphs, list
+	// items, table rows, <br>...) as line breaks.
+	Blocks bool
+}
+
+// CleanTextOptions are the options used by CleanText.
+var CleanTextOptions = TextOptions{Collapse: true, Normalize: true, SkipNonContent: true, Blocks: true}
+
+var nonContentElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Template: true,
+}
+
+var blockElements = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Blockquote: true,
+	atom.Br:         true,
+	atom.Dd:         true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Header:     true,
+	atom.Hr:         true,
+	atom.Li:         true,
+	atom.Main:       true,
+	atom.Nav:        true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+}
+
+var spaceReplacer = strings.NewReplacer(
+	"\u00A0", " ", // no-break space
+	"\u2007", " ", // figure space
+	"\u202F", " ", // narrow no-break space
+	"\u200B", " ", // zero width space
+	"\u2060", " ", // word joiner
+	"\uFEFF", " ", // zero width no-break space
+)
+
+// blockBreak marks the boundaries of the block elements while the text is
+// collected, the HTML parser never leaves a NUL in a text node.
+const blockBreak = '\x00'
+
+// TextWith returns the text of the node cleaned up according to opts.
+func (d *Doc) TextWith(opts TextOptions) string {
+	if !d.Found() {
+		return ""
+	}
+	var b strings.Builder
+	var f func(node *html.Node)
+	f = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			b.WriteString(node.Data)
+			return
+		}
+		if node.Type == html.ElementNode {
+			if opts.SkipNonContent && nonContentElements[node.DataAtom] {
+				return
+			}
+			if opts.Blocks && blockElements[node.DataAtom] {
+				b.WriteByte(blockBreak)
+				defer b.WriteByte(blockBreak)
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			f(child)
+		}
+	}
+	f(d.Node)
+	text := b.String()
+	if opts.Normalize {
+		text = spaceReplacer.Replace(text)
+	}
+	if !opts.Collapse {
+		return strings.ReplaceAll(text, string(blockBreak), "\n")
+	}
+	lines := []string{}
+	for _, line := range strings.Split(text, string(blockBreak)) {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CleanText returns the displayed text of the node, with the white space
+// collapsed and a line break between blocks.
+func (d *Doc) CleanText() string {
+	return d.TextWith(CleanTextOptions)
+}
@@ -0,0 +1,297 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ErrEmptyValue is wrapped by the field errors of Unmarshal when the element
+// of a field is found but its value is empty.
+var ErrEmptyValue = errors.New("the value is empty")
+
+// Parser converts the text of a field into the value stored in it, see the
+// parse tag of Unmarshal.
+type Parser func(value string) (interface{}, error)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = make(map[string]Parser)
+)
+
+// RegisterParser makes parse available to the parse tag of Unmarshal under
+// name. It panics if name is empty or already registered.
+func RegisterParser(name string, parse Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	if name == "" {
+		panic("doc: parser name can't be empty")
+	}
+	if _, dup := parsers[name]; dup {
+		panic("doc: parser " + name + " registered twice")
+	}
+	parsers[name] = parse
+}
+
+// LookupParser returns the parser registered under name.
+func LookupParser(name string) (Parser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	parse, ok := parsers[name]
+	return parse, ok
+}
+
+// FieldError is the reason why a field couldn't be filled in by Unmarshal.
+type FieldError struct {
+	Field string
+	// Required tells whether the field has the required tag.
+	Required bool
+	Err      error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors is returned by Unmarshal when some fields can't be filled in.
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Required returns the errors of the required fields, or nil if all of them
+// were filled in.
+func (e FieldErrors) Required() error {
+	required := FieldErrors{}
+	for _, err := range e {
+		if err.Required {
+			required = append(required, err)
+		}
+	}
+	if len(required) == 0 {
+		return nil
+	}
+	return required
+}
+
+var (
+	docType  = reflect.TypeOf((*Doc)(nil))
+	timeType = reflect.TypeOf(time.Time{})
+	urlType  = reflect.TypeOf((*url.URL)(nil))
+)
+
+// Unmarshal fills in the exported fields of the struct pointed to by v with
+// the data of d, as described by their tags:
+//
+//	css:"h2 a"          the element the value is taken from, alternative
+//	                    selectors separated by ";" are tried in order
+//	xpath:"h4/text()"   the same, with an XPath expression
+//	label:"Edad"        the value of a label in the element (see Fields),
+//	                    alternative labels are separated by ";"
+//	attr:"href"         use an attribute instead of the clean text
+//	parse:"mordate"     convert the text with a parser (see RegisterParser)
+//	layout:"2006-01-02" the layout of a time.Time field, RFC 3339 by default
+//	abs:"true"          resolve the URL against the base URL of d
+//	required:"true"     a missing or empty value is an error
+//
+// The fields can be strings, booleans, numbers, time.Time, *url.URL, *Doc (the
+// element itself) or the type returned by their parser. Fields without any
+// of the css, xpath, label or attr tags are left alone.
+//
+// A missing element or an empty value leaves the field untouched unless it
+// is required. The problems are reported together as FieldErrors.
+func Unmarshal(d *Doc, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("doc: Unmarshal needs a non-nil pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	errs := FieldErrors{}
+	fields := make(map[*html.Node]Fields)
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag
+		if tag.Get("css") == "" && tag.Get("xpath") == "" && tag.Get("label") == "" && tag.Get("attr") == "" {
+			continue
+		}
+		required := tag.Get("required") == "true"
+		err := unmarshalField(d, rv.Field(i), tag, fields)
+		if err == nil {
+			continue
+		}
+		if !required && (errors.Is(err, ErrNoMatch) || errors.Is(err, ErrEmptyValue)) {
+			continue
+		}
+		errs = append(errs, &FieldError{Field: sf.Name, Required: required, Err: err})
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+func unmarshalField(d *Doc, field reflect.Value, tag reflect.StructTag, fields map[*html.Node]Fields) error {
+	node, err := selectNode(d, tag)
+	if err != nil {
+		return err
+	}
+	if label := tag.Get("label"); label != "" {
+		f, ok := fields[node.Node]
+		if !ok {
+			f = node.Fields()
+			fields[node.Node] = f
+		}
+		if node = f.Get(strings.Split(label, ";")...); !node.Found() {
+			return fmt.Errorf("label %q: %w", label, ErrNoMatch)
+		}
+	}
+	if field.Type() == docType {
+		field.Set(reflect.ValueOf(node))
+		return nil
+	}
+	var text string
+	if attr := tag.Get("attr"); attr != "" {
+		value, ok := node.attr(attr)
+		if !ok {
+			return fmt.Errorf("the %s attribute is missing: %w", attr, ErrEmptyValue)
+		}
+		text = strings.TrimSpace(value)
+	} else {
+		text = node.CleanText()
+	}
+	if text == "" {
+		return ErrEmptyValue
+	}
+	var value interface{} = text
+	if name := tag.Get("parse"); name != "" {
+		parse, ok := LookupParser(name)
+		if !ok {
+			return fmt.Errorf("unknown parser %q", name)
+		}
+		if value, err = parse(text); err != nil {
+			return err
+		}
+	}
+	return setField(node, field, value, tag)
+}
+
+func selectNode(d *Doc, tag reflect.StructTag) (*Doc, error) {
+	if css := tag.Get("css"); css != "" {
+		var err error
+		for _, query := range strings.Split(css, ";") {
+			var node *Doc
+			node, err = d.QueryE(strings.TrimSpace(query))
+			if !errors.Is(err, ErrNoMatch) {
+				return node, err
+			}
+		}
+		return nil, err
+	}
+	if expr := tag.Get("xpath"); expr != "" {
+		return d.XPathE(expr)
+	}
+	return d, nil
+}
+
+// setField stores value in field. The strings are converted according to the
+// type of the field, the values returned by the parsers must be assignable.
+func setField(node *Doc, field reflect.Value, value interface{}, tag reflect.StructTag) error {
+	s, ok := value.(string)
+	if !ok {
+		rv := reflect.ValueOf(value)
+		if !rv.IsValid() || !rv.Type().AssignableTo(field.Type()) {
+			return fmt.Errorf("can't store a %T in a %s field", value, field.Type())
+		}
+		field.Set(rv)
+		return nil
+	}
+	abs := tag.Get("abs") == "true"
+	switch {
+	case field.Type() == urlType:
+		var u *url.URL
+		var err error
+		if abs {
+			u, err = node.ResolveURL(s)
+		} else {
+			u, err = url.Parse(s)
+		}
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(u))
+	case field.Type() == timeType:
+		layout := tag.Get("layout")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+	case field.Kind() == reflect.String:
+		if abs {
+			u, err := node.ResolveURL(s)
+			if err != nil {
+				return err
+			}
+			s = u.String()
+		}
+		field.SetString(s)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.CanInt():
+		i, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case field.CanUint():
+		u, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case field.CanFloat():
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("can't store a string in a %s field", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package doc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// ExprError is returned by the strict XPath queries when the expression
+// can't be compiled.
+type ExprError struct {
+	Expr string
+	Err  error
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("invalid XPath expression %q: %s", e.Expr, e.Err)
+}
+
+func (e *ExprError) Unwrap() error {
+	return e.Err
+}
+
+// expressions caches the compiled XPath expressions the same way selectors
+// does for the CSS selectors.
+var expressions sync.Map
+
+func compileXPath(expr string) (*xpath.Expr, error) {
+	if cached, ok := expressions.Load(expr); ok {
+		if err, ok := cached.(error); ok {
+			return nil, err
+		}
+		return cached.(*xpath.Expr), nil
+	}
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		err = &ExprError{Expr: expr, Err: err}
+		expressions.Store(expr, err)
+		return nil, err
+	}
+	expressions.Store(expr, compiled)
+	return compiled, nil
+}
+
+// XPath returns the first node selected by expr, evaluated with d as the
+// context node. Text nodes can be selected (e.g. "h4/text()[1]") and so can
+// attributes, which are returned as text nodes holding their value.
+func (d *Doc) XPath(expr string) *Doc {
+	node, _ := d.XPathE(expr)
+	return node
+}
+
+// XPathE is the strict version of XPath, it returns an *ExprError if the
+// expression is invalid and an error wrapping ErrNoMatch if nothing matches.
+func (d *Doc) XPathE(expr string) (*Doc, error) {
+	compiled, err := compileXPath(expr)
+	if err != nil {
+		return d.wrap(nil), err
+	}
+	if d.Found() {
+		iter := compiled.Select(newNavigator(d.Node))
+		if iter.MoveNext() {
+			return d.wrap(iter.Current().(*navigator).node()), nil
+		}
+	}
+	return d.wrap(nil), fmt.Errorf("%q: %w", expr, ErrNoMatch)
+}
+
+func (d *Doc) XPathAll(expr string) []*Doc {
+	docs, _ := d.XPathAllE(expr)
+	return docs
+}
+
+// XPathAllE is the strict version of XPathAll, it returns an *ExprError if
+// the expression is invalid. Matching nothing is not an error.
+func (d *Doc) XPathAllE(expr string) ([]*Doc, error) {
+	compiled, err := compileXPath(expr)
+	if err != nil {
+		return []*Doc{}, err
+	}
+	docs := []*Doc{}
+	if !d.Found() {
+		return docs, nil
+	}
+	iter := compiled.Select(newNavigator(d.Node))
+	for iter.MoveNext() {
+		docs = append(docs, d.wrap(iter.Current().(*navigator).node()))
+	}
+	return docs, nil
+}
+
+// navigator implements xpath.NodeNavigator over an HTML tree. root is the
+// node the expressions are evaluated from, it is also the root of the absolute
+// paths; attr is the index of the current attribute or -1 if the current node
+// is curr itself.
+type navigator struct {
+	root, curr *html.Node
+	attr       int
+}
+
+func newNavigator(root *html.Node) *navigator {
+	return &navigator{root: root, curr: root, attr: -1}
+}
+
+// node returns the current node, attributes are returned as detached text
+// nodes holding their value.
+func (n *navigator) node() *html.Node {
+	if n.attr != -1 {
+		return &html.Node{Type: html.TextNode, Data: n.curr.Attr[n.attr].Val}
+	}
+	return n.curr
+}
+
+func (n *navigator) NodeType() xpath.NodeType {
+	switch n.curr.Type {
+	case html.CommentNode:
+		return xpath.CommentNode
+	case html.TextNode:
+		return xpath.TextNode
+	case html.DocumentNode, html.DoctypeNode:
+		return xpath.RootNode
+	case html.ElementNode:
+		if n.attr != -1 {
+			return xpath.AttributeNode
+		}
+		return xpath.ElementNode
+	default:
+		return xpath.TextNode
+	}
+}
+
+func (n *navigator) LocalName() string {
+	if n.attr != -1 {
+		return n.curr.Attr[n.attr].Key
+	}
+	return n.curr.Data
+}
+
+func (n *navigator) Prefix() string {
+	return ""
+}
+
+func (n *navigator) Value() string {
+	switch n.curr.Type {
+	case html.CommentNode, html.TextNode:
+		return n.curr.Data
+	case html.ElementNode:
+		if n.attr != -1 {
+			return n.curr.Attr[n.attr].Val
+		}
+		return (&Doc{Node: n.curr}).Text()
+	case html.DocumentNode:
+		return (&Doc{Node: n.curr}).Text()
+	default:
+		return ""
+	}
+}
+
+func (n *navigator) Copy() xpath.NodeNavigator {
+	c := *n
+	return &c
+}
+
+func (n *navigator) MoveToRoot() {
+	n.curr = n.root
+	n.attr = -1
+}
+
+func (n *navigator) MoveToParent() bool {
+	if n.attr != -1 {
+		n.attr = -1
+		return true
+	}
+	if n.curr.Parent == nil {
+		return false
+	}
+	n.curr = n.curr.Parent
+	return true
+}
+
+func (n *navigator) MoveToNextAttribute() bool {
+	if n.attr >= len(n.curr.Attr)-1 {
+		return false
+	}
+	n.attr++
+	return true
+}
+
+func (n *navigator) MoveToChild() bool {
+	if n.attr != -1 || n.curr.FirstChild == nil {
+		return false
+	}
+	n.curr = n.curr.FirstChild
+	return true
+}
+
+func (n *navigator) MoveToFirst() bool {
+	if n.attr != -1 || n.curr.PrevSibling == nil {
+		return false
+	}
+	for n.curr.PrevSibling != nil {
+		n.curr = n.curr.PrevSibling
+	}
+	return true
+}
+
+func (n *navigator) MoveToNext() bool {
+	if n.attr != -1 || n.curr.NextSibling == nil {
+		return false
+	}
+	n.curr = n.curr.NextSibling
+	return true
+}
+
+func (n *navigator) MoveToPrevious() bool {
+	if n.attr != -1 || n.curr.PrevSibling == nil {
+		return false
+	}
+	n.curr = n.curr.PrevSibling
+	return true
+}
+
+func (n *navigator) MoveTo(other xpath.NodeNavigator) bool {
+	o, ok := other.(*navigator)
+	if !ok || o.root != n.root {
+		return false
+	}
+	n.curr = o.curr
+	n.attr = o.attr
+	return true
+}
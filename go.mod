@@ -7,3 +7,7 @@ require (
 	golang.org/x/net v0.0.0-20220722155237-a158d28d115b
 	golang.org/x/text v0.3.7
 )
+
+require github.com/antchfx/xpath v1.3.5
+
+require gopkg.in/yaml.v3 v3.0.1
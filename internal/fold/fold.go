@@ -0,0 +1,28 @@
+// Package fold folds the Spanish texts of the fiscalías for comparisons, so
+// that "MARÍA", "María" and "maria" are the same word.
+package fold
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// RemoveAccents returns s in Unicode NFC without the accents and the other
+// combining marks, e.g. "Peña Núñez" is "Pena Nunez".
+func RemoveAccents(s string) string {
+	// A transformer keeps state, so one is made for every call.
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if stripped, _, err := transform.String(t, s); err == nil {
+		return stripped
+	}
+	return s
+}
+
+// Fold returns s in lower case and without accents.
+func Fold(s string) string {
+	return strings.ToLower(RemoveAccents(s))
+}
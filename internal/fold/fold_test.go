@@ -0,0 +1,23 @@
+package fold
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	testCases := []struct {
+		value  string
+		wanted string
+	}{
+		{"MARÍA JOSÉ", "maria jose"},
+		{"Peña Núñez", "pena nunez"},
+		// The decomposed accents are removed too.
+		{"Jose\u0301", "jose"},
+		{"miércoles 1º", "miercoles 1º"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			if got := Fold(tc.value); got != tc.wanted {
+				t.Errorf("got %q; want %q", got, tc.wanted)
+			}
+		})
+	}
+}
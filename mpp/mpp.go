@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"net/url"
 	"time"
+
+	"github.com/midir99/rastreadora/ages"
+	"github.com/midir99/rastreadora/dates"
+	"github.com/midir99/rastreadora/names"
 )
 
 type State string
@@ -79,7 +83,12 @@ const (
 )
 
 type MissingPersonPoster struct {
+	// MpName is the display form of the name, GivenNames, PaternalSurname
+	// and MaternalSurname are its parts.
 	MpName                           string
+	GivenNames                       string
+	PaternalSurname                  string
+	MaternalSurname                  string
 	MpHeight                         int
 	MpWeight                         int
 	MpPhysicalBuild                  PhysicalBuild
@@ -101,19 +110,72 @@ type MissingPersonPoster struct {
 	PoPostPublicationDate            time.Time
 	PoPosterUrl                      *url.URL
 	IsMultiple                       bool
+	// MpSexInferred tells that MpSex was not stated by the source but
+	// inferred from the name and the wording, MpSexConfidence is then the
+	// probability that it is right.
+	MpSexInferred   bool
+	MpSexConfidence float64
+	// MpAgeMonths are the months of age on top of MpAgeWhenDisappeared,
+	// e.g. 6 for "1 año 6 meses". MpAgeApproximate tells that the age is an
+	// estimate, e.g. "aprox. 40 años" or the middle of "entre 30 y 35 años".
+	// MpAgeStated tells that the source stated the age and MpAgeDerived that
+	// it was computed from MpDob and MissingDate, so that an age of 0 years,
+	// e.g. "8 meses", is told apart from a missing one.
+	MpAgeMonths      int
+	MpAgeApproximate bool
+	MpAgeStated      bool
+	MpAgeDerived     bool
+	// MpDobPrecision, MissingDatePrecision and PoPostPublicationDatePrecision
+	// tell which parts of the dates the source wrote, e.g. only the month
+	// and the year of "marzo de 2022"; they are zero when it is unknown.
+	MpDobPrecision                 dates.Precision
+	MissingDatePrecision           dates.Precision
+	PoPostPublicationDatePrecision dates.Precision
 }
 
-func (m MissingPersonPoster) MarshalJSON() ([]byte, error) {
-	var dob, missingDate, pubDate, postUrl, posterUrl string
-	if !m.MpDob.IsZero() {
-		dob = m.MpDob.Format("2006-01-02")
+// HasAge tells whether the age of the person is known.
+func (m MissingPersonPoster) HasAge() bool {
+	return m.MpAgeStated || m.MpAgeDerived || m.MpAgeWhenDisappeared != 0 || m.MpAgeMonths != 0
+}
+
+// formatDate returns t as an RFC 3339 timestamp if it has the time of the
+// day, or else as a date. Without a precision a time at midnight is taken as
+// a date. It is empty for the zero time.
+func formatDate(t time.Time, precision dates.Precision) string {
+	if t.IsZero() {
+		return ""
 	}
-	if !m.MissingDate.IsZero() {
-		missingDate = m.MissingDate.Format("2006-01-02")
+	switch precision {
+	case dates.PrecisionTime:
+		return t.Format(time.RFC3339)
+	case dates.PrecisionYear, dates.PrecisionMonth, dates.PrecisionDay:
+		return t.Format("2006-01-02")
 	}
-	if !m.PoPostPublicationDate.IsZero() {
-		pubDate = m.PoPostPublicationDate.Format("2006-01-02")
+	if hour, min, sec := t.Clock(); hour == 0 && min == 0 && sec == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
 	}
+	return t.Format(time.RFC3339)
+}
+
+// formatPrecision returns the name of the precision of a date, it is empty
+// for the zero time or an unknown precision.
+func formatPrecision(t time.Time, precision dates.Precision) string {
+	if t.IsZero() || precision == 0 {
+		return ""
+	}
+	return precision.String()
+}
+
+// MarshalJSON writes the dates as "2006-01-02", or as RFC 3339 timestamps when
+// the source tells the time, e.g. "2022-06-30T16:55:37-05:00" for the
+// publication of a post. Their precisions are written next to them, e.g.
+// "month" for a date that is the 1st of the month only because the source
+// wrote no day.
+func (m MissingPersonPoster) MarshalJSON() ([]byte, error) {
+	var postUrl, posterUrl string
+	dob := formatDate(m.MpDob, m.MpDobPrecision)
+	missingDate := formatDate(m.MissingDate, m.MissingDatePrecision)
+	pubDate := formatDate(m.PoPostPublicationDate, m.PoPostPublicationDatePrecision)
 	if m.PoPostUrl != nil {
 		postUrl = m.PoPostUrl.String()
 	}
@@ -121,37 +183,57 @@ func (m MissingPersonPoster) MarshalJSON() ([]byte, error) {
 		posterUrl = m.PoPosterUrl.String()
 	}
 	basicMpp := struct {
-		MpName                           string `json:"mp_name"`
-		MpHeight                         int    `json:"mp_height,omitempty"`
-		MpWeight                         int    `json:"mp_weight,omitempty"`
-		MpPhysicalBuild                  string `json:"mp_physical_build,omitempty"`
-		MpComplexion                     string `json:"mp_complexion,omitempty"`
-		MpSex                            string `json:"mp_sex,omitempty"`
-		MpDob                            string `json:"mp_dob,omitempty"`
-		MpAgeWhenDisappeared             int    `json:"mp_age_when_disappeared,omitempty"`
-		MpEyesDescription                string `json:"mp_eyes_description,omitempty"`
-		MpHairDescription                string `json:"mp_hair_description,omitempty"`
-		MpOutfitDescription              string `json:"mp_outfit_description,omitempty"`
-		MpIdentifyingCharacteristics     string `json:"mp_identifying_characteristics,omitempty"`
-		CircumstancesBehindDissapearance string `json:"circumstances_behind_dissapearance,omitempty"`
-		MissingFrom                      string `json:"missing_from,omitempty"`
-		MissingDate                      string `json:"missing_date,omitempty"`
-		Found                            bool   `json:"found,omitempty"`
-		AlertType                        string `json:"alert_type,omitempty"`
-		PoState                          string `json:"po_state"`
-		PoPostUrl                        string `json:"po_post_url,omitempty"`
-		PoPostPublicationDate            string `json:"po_post_publication_date,omitempty"`
-		PoPosterUrl                      string `json:"po_poster_url,omitempty"`
-		IsMultiple                       bool   `json:"is_multiple,omitempty"`
+		MpName                           string  `json:"mp_name"`
+		GivenNames                       string  `json:"given_names,omitempty"`
+		PaternalSurname                  string  `json:"paternal_surname,omitempty"`
+		MaternalSurname                  string  `json:"maternal_surname,omitempty"`
+		MpHeight                         int     `json:"mp_height,omitempty"`
+		MpWeight                         int     `json:"mp_weight,omitempty"`
+		MpPhysicalBuild                  string  `json:"mp_physical_build,omitempty"`
+		MpComplexion                     string  `json:"mp_complexion,omitempty"`
+		MpSex                            string  `json:"mp_sex,omitempty"`
+		MpSexInferred                    bool    `json:"mp_sex_inferred,omitempty"`
+		MpSexConfidence                  float64 `json:"mp_sex_confidence,omitempty"`
+		MpDob                            string  `json:"mp_dob,omitempty"`
+		MpDobPrecision                   string  `json:"mp_dob_precision,omitempty"`
+		MpAgeWhenDisappeared             *int    `json:"mp_age_when_disappeared,omitempty"`
+		MpAgeMonths                      *int    `json:"mp_age_months,omitempty"`
+		MpAgeApproximate                 bool    `json:"mp_age_approximate,omitempty"`
+		MpAgeStated                      bool    `json:"mp_age_stated,omitempty"`
+		MpAgeDerived                     bool    `json:"mp_age_derived,omitempty"`
+		MpEyesDescription                string  `json:"mp_eyes_description,omitempty"`
+		MpHairDescription                string  `json:"mp_hair_description,omitempty"`
+		MpOutfitDescription              string  `json:"mp_outfit_description,omitempty"`
+		MpIdentifyingCharacteristics     string  `json:"mp_identifying_characteristics,omitempty"`
+		CircumstancesBehindDissapearance string  `json:"circumstances_behind_dissapearance,omitempty"`
+		MissingFrom                      string  `json:"missing_from,omitempty"`
+		MissingDate                      string  `json:"missing_date,omitempty"`
+		MissingDatePrecision             string  `json:"missing_date_precision,omitempty"`
+		Found                            bool    `json:"found,omitempty"`
+		AlertType                        string  `json:"alert_type,omitempty"`
+		PoState                          string  `json:"po_state"`
+		PoPostUrl                        string  `json:"po_post_url,omitempty"`
+		PoPostPublicationDate            string  `json:"po_post_publication_date,omitempty"`
+		PoPostPublicationDatePrecision   string  `json:"po_post_publication_date_precision,omitempty"`
+		PoPosterUrl                      string  `json:"po_poster_url,omitempty"`
+		IsMultiple                       bool    `json:"is_multiple,omitempty"`
 	}{
 		MpName:                           m.MpName,
+		GivenNames:                       m.GivenNames,
+		PaternalSurname:                  m.PaternalSurname,
+		MaternalSurname:                  m.MaternalSurname,
 		MpHeight:                         m.MpHeight,
 		MpWeight:                         m.MpWeight,
 		MpPhysicalBuild:                  string(m.MpPhysicalBuild),
 		MpComplexion:                     string(m.MpComplexion),
 		MpSex:                            string(m.MpSex),
+		MpSexInferred:                    m.MpSexInferred,
+		MpSexConfidence:                  m.MpSexConfidence,
 		MpDob:                            dob,
-		MpAgeWhenDisappeared:             m.MpAgeWhenDisappeared,
+		MpDobPrecision:                   formatPrecision(m.MpDob, m.MpDobPrecision),
+		MpAgeApproximate:                 m.MpAgeApproximate,
+		MpAgeStated:                      m.MpAgeStated,
+		MpAgeDerived:                     m.MpAgeDerived,
 		MpEyesDescription:                m.MpEyesDescription,
 		MpHairDescription:                m.MpHairDescription,
 		MpOutfitDescription:              m.MpOutfitDescription,
@@ -159,13 +241,57 @@ func (m MissingPersonPoster) MarshalJSON() ([]byte, error) {
 		CircumstancesBehindDissapearance: m.CircumstancesBehindDissapearance,
 		MissingFrom:                      m.MissingFrom,
 		MissingDate:                      missingDate,
+		MissingDatePrecision:             formatPrecision(m.MissingDate, m.MissingDatePrecision),
 		Found:                            m.Found,
 		AlertType:                        string(m.AlertType),
 		PoState:                          string(m.PoState),
 		PoPostUrl:                        postUrl,
 		PoPostPublicationDate:            pubDate,
+		PoPostPublicationDatePrecision:   formatPrecision(m.PoPostPublicationDate, m.PoPostPublicationDatePrecision),
 		PoPosterUrl:                      posterUrl,
 		IsMultiple:                       m.IsMultiple,
 	}
+	// A known age of 0 years is written, unlike a missing one.
+	if m.HasAge() {
+		years, months := m.MpAgeWhenDisappeared, m.MpAgeMonths
+		basicMpp.MpAgeWhenDisappeared, basicMpp.MpAgeMonths = &years, &months
+	}
 	return json.Marshal(basicMpp)
 }
+
+// SetName sets MpName to the display form of the full name (see
+// names.Normalize) and fills in its parts.
+func (m *MissingPersonPoster) SetName(fullName string) {
+	name := names.Parse(fullName)
+	m.MpName = name.String()
+	m.GivenNames = name.GivenNames
+	m.PaternalSurname = name.PaternalSurname
+	m.MaternalSurname = name.MaternalSurname
+}
+
+// SetAge sets MpAgeWhenDisappeared and MpAgeMonths to the age stated by the
+// source (see ages.Age.YearsAndMonths), MpAgeApproximate if it is qualified
+// or a range and MpAgeStated.
+func (m *MissingPersonPoster) SetAge(age ages.Age) {
+	m.MpAgeWhenDisappeared, m.MpAgeMonths = age.YearsAndMonths()
+	m.MpAgeApproximate = age.Approximate || age.IsRange()
+	m.MpAgeStated, m.MpAgeDerived = true, false
+}
+
+// SetDob sets MpDob and MpDobPrecision to the date of birth stated by the
+// source.
+func (m *MissingPersonPoster) SetDob(date dates.Date) {
+	m.MpDob, m.MpDobPrecision = date.Time, date.Precision
+}
+
+// SetMissingDate sets MissingDate and MissingDatePrecision to the date the
+// person went missing.
+func (m *MissingPersonPoster) SetMissingDate(date dates.Date) {
+	m.MissingDate, m.MissingDatePrecision = date.Time, date.Precision
+}
+
+// SetPostPublicationDate sets PoPostPublicationDate and
+// PoPostPublicationDatePrecision to the publication date of the post.
+func (m *MissingPersonPoster) SetPostPublicationDate(date dates.Date) {
+	m.PoPostPublicationDate, m.PoPostPublicationDatePrecision = date.Time, date.Precision
+}
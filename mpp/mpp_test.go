@@ -0,0 +1,117 @@
+package mpp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midir99/rastreadora/ages"
+	"github.com/midir99/rastreadora/dates"
+)
+
+func TestStateLocation(t *testing.T) {
+	testCases := []struct {
+		state  State
+		wanted string
+	}{
+		{StateCiudadDeMexico, "America/Mexico_City"},
+		{StateQuintanaRoo, "America/Cancun"},
+		{StateBajaCalifornia, "America/Tijuana"},
+		{State("MX-XXX"), "UTC"},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.state), func(t *testing.T) {
+			if got := tc.state.Location().String(); got != tc.wanted {
+				t.Errorf("got %s; want %s", got, tc.wanted)
+			}
+			// The zone is loaded once.
+			if tc.state.Location() != tc.state.Location() {
+				t.Errorf("got a new *time.Location on every call; want the same one")
+			}
+		})
+	}
+}
+
+func TestMarshalJSONDates(t *testing.T) {
+	loc := StateMorelos.Location()
+	m := MissingPersonPoster{
+		MpDob:                 time.Date(1981, time.April, 2, 0, 0, 0, 0, loc),
+		PoPostPublicationDate: time.Date(2022, time.June, 30, 16, 55, 37, 0, loc),
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	for _, wanted := range []string{
+		`"mp_dob":"1981-04-02"`,
+		`"po_post_publication_date":"2022-06-30T16:55:37-05:00"`,
+	} {
+		if !strings.Contains(string(data), wanted) {
+			t.Errorf("got %s; want it to contain %s", data, wanted)
+		}
+	}
+	if strings.Contains(string(data), "missing_date") {
+		t.Errorf("got %s; want no missing_date", data)
+	}
+}
+
+func TestMarshalJSONDatePrecision(t *testing.T) {
+	loc := StateMorelos.Location()
+	m := MissingPersonPoster{}
+	m.SetDob(dates.Date{Time: time.Date(1981, time.January, 1, 0, 0, 0, 0, loc), Precision: dates.PrecisionYear})
+	m.SetMissingDate(dates.Date{Time: time.Date(2022, time.March, 1, 0, 0, 0, 0, loc), Precision: dates.PrecisionMonth})
+	m.SetPostPublicationDate(dates.Date{Time: time.Date(2022, time.June, 30, 0, 0, 0, 0, loc), Precision: dates.PrecisionTime})
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	for _, wanted := range []string{
+		`"mp_dob":"1981-01-01","mp_dob_precision":"year"`,
+		`"missing_date":"2022-03-01","missing_date_precision":"month"`,
+		// A timestamp at midnight is still a timestamp.
+		`"po_post_publication_date":"2022-06-30T00:00:00-05:00","po_post_publication_date_precision":"time"`,
+	} {
+		if !strings.Contains(string(data), wanted) {
+			t.Errorf("got %s; want it to contain %s", data, wanted)
+		}
+	}
+}
+
+func TestMarshalJSONAge(t *testing.T) {
+	testCases := []struct {
+		name   string
+		age    string
+		wanted []string
+	}{
+		{"months", "8 meses", []string{`"mp_age_when_disappeared":0`, `"mp_age_months":8`, `"mp_age_stated":true`}},
+		{"years and months", "1 año 6 meses", []string{`"mp_age_when_disappeared":1`, `"mp_age_months":6`}},
+		{"range", "entre 30 y 35 años", []string{`"mp_age_when_disappeared":32`, `"mp_age_months":6`, `"mp_age_approximate":true`}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			age, err := ages.Parse(tc.age)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := MissingPersonPoster{}
+			m.SetAge(age)
+			data, err := json.Marshal(m)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			for _, wanted := range tc.wanted {
+				if !strings.Contains(string(data), wanted) {
+					t.Errorf("got %s; want it to contain %s", data, wanted)
+				}
+			}
+		})
+	}
+	data, err := json.Marshal(MissingPersonPoster{})
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if strings.Contains(string(data), "mp_age") {
+		t.Errorf("got %s; want no age", data)
+	}
+}
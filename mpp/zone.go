@@ -0,0 +1,76 @@
+package mpp
+
+import (
+	"time"
+	// The zones of Mexico are embedded, so they are known even where the
+	// system has no zone database, e.g. on Windows.
+	_ "time/tzdata"
+)
+
+// zones are the IANA time zones of the states, for the states with several
+// zones it is the one of the capital.
+var zones = map[State]string{
+	StateCiudadDeMexico:             "America/Mexico_City",
+	StateAguascalientes:             "America/Mexico_City",
+	StateBajaCalifornia:             "America/Tijuana",
+	StateBajaCaliforniaSur:          "America/Mazatlan",
+	StateCampeche:                   "America/Merida",
+	StateCoahuilaDeZaragoza:         "America/Monterrey",
+	StateColima:                     "America/Mexico_City",
+	StateChiapas:                    "America/Mexico_City",
+	StateChihuahua:                  "America/Chihuahua",
+	StateDurango:                    "America/Monterrey",
+	StateGuanajuato:                 "America/Mexico_City",
+	StateGuerrero:                   "America/Mexico_City",
+	StateHidalgo:                    "America/Mexico_City",
+	StateJalisco:                    "America/Mexico_City",
+	StateMexico:                     "America/Mexico_City",
+	StateMichoacanDeOcampo:          "America/Mexico_City",
+	StateMorelos:                    "America/Mexico_City",
+	StateNayarit:                    "America/Mazatlan",
+	StateNuevoLeon:                  "America/Monterrey",
+	StateOaxaca:                     "America/Mexico_City",
+	StatePuebla:                     "America/Mexico_City",
+	StateQueretaro:                  "America/Mexico_City",
+	StateQuintanaRoo:                "America/Cancun",
+	StateSanLuisPotosi:              "America/Mexico_City",
+	StateSinaloa:                    "America/Mazatlan",
+	StateSonora:                     "America/Hermosillo",
+	StateTabasco:                    "America/Mexico_City",
+	StateTamaulipas:                 "America/Monterrey",
+	StateTlaxcala:                   "America/Mexico_City",
+	StateVeracruzDeIgnacioDeLaLlave: "America/Mexico_City",
+	StateYucatan:                    "America/Merida",
+	StateZacatecas:                  "America/Mexico_City",
+}
+
+// locations are the zones of the states, loaded once so that parsing a date
+// doesn't read the zone database.
+var locations = loadLocations()
+
+func loadLocations() map[State]*time.Location {
+	locations := make(map[State]*time.Location, len(zones))
+	for state, name := range zones {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			loc = time.UTC
+		}
+		locations[state] = loc
+	}
+	return locations
+}
+
+// Known tells whether s is one of the states of Mexico, e.g. MX-GRO.
+func (s State) Known() bool {
+	_, ok := zones[s]
+	return ok
+}
+
+// Location returns the time zone of the state, the dates published by its
+// fiscalía are in it. It is UTC for an unknown state.
+func (s State) Location() *time.Location {
+	if loc, ok := locations[s]; ok {
+		return loc
+	}
+	return time.UTC
+}
@@ -0,0 +1,140 @@
+// Package names normalizes the Spanish names of people and splits them into
+// given names and the paternal and maternal surnames, e.g. "MARÍA DE LA LUZ
+// PÉREZ DE LA CRUZ" is "María de la Luz", "Pérez" and "de la Cruz".
+package names
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/midir99/rastreadora/internal/fold"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// particles are the words that are written in lower case and belong to the
+// word that follows them, e.g. "de la Cruz" or "del Río". "y" joins two
+// surnames into one, e.g. "Ortega y Gasset".
+var particles = map[string]bool{
+	"de":  true,
+	"del": true,
+	"la":  true,
+	"las": true,
+	"los": true,
+	"y":   true,
+}
+
+// devotionalNames are the words that follow a particle in the compound given
+// names, e.g. "José de Jesús" or "María de los Ángeles", so they aren't taken
+// as surnames. They are written without accents.
+var devotionalNames = map[string]bool{
+	"angeles":   true,
+	"carmen":    true,
+	"dios":      true,
+	"dolores":   true,
+	"guadalupe": true,
+	"jesus":     true,
+	"lourdes":   true,
+	"luz":       true,
+	"pilar":     true,
+	"refugio":   true,
+	"rosario":   true,
+	"socorro":   true,
+}
+
+// Name is a name split in its parts, the surnames can be empty.
+type Name struct {
+	GivenNames      string
+	PaternalSurname string
+	MaternalSurname string
+}
+
+// String returns the display form of the name: the given names followed by
+// the surnames.
+func (n Name) String() string {
+	parts := []string{}
+	for _, part := range []string{n.GivenNames, n.PaternalSurname, n.MaternalSurname} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Normalize returns name in Unicode NFC with single spaces, every word
+// capitalized but the particles (de, del, de la, de los, y), which are in
+// lower case unless they start the name. The annotations in parentheses,
+// e.g. the state in "Mateo Sánchez Arce (Gto)", and the punctuation around
+// the name are dropped.
+func Normalize(name string) string {
+	return strings.Join(words(name), " ")
+}
+
+// Parse splits a name written as the given names followed by the surnames,
+// as the fiscalías do. The last word is the maternal surname and the one
+// before it the paternal surname, each with the particles that precede it;
+// a name of two words has no maternal surname and a single word is a given
+// name. The compound given names like "María del Carmen" are kept whole. The
+// parts are normalized like Normalize does.
+func Parse(name string) Name {
+	ws := words(name)
+	var surnames []string
+	end := len(ws)
+	for len(surnames) < 2 && end > 1 {
+		start := surnameStart(ws[:end])
+		if start == 0 {
+			// The given names can't be empty, the particles at the
+			// start are taken as a given name.
+			break
+		}
+		if start < end-1 && devotionalNames[fold.Fold(ws[end-1])] {
+			break
+		}
+		surnames = append(surnames, strings.Join(ws[start:end], " "))
+		end = start
+	}
+	n := Name{GivenNames: strings.Join(ws[:end], " ")}
+	switch len(surnames) {
+	case 1:
+		n.PaternalSurname = surnames[0]
+	case 2:
+		n.PaternalSurname, n.MaternalSurname = surnames[1], surnames[0]
+	}
+	return n
+}
+
+// surnameStart returns the index of the first word of the surname that ends
+// ws: its last word, the particles before it and, if they are joined by
+// "y", the surname before them.
+func surnameStart(ws []string) int {
+	start := len(ws) - 1
+	for start > 0 && particles[strings.ToLower(ws[start-1])] {
+		start--
+		if strings.ToLower(ws[start]) == "y" && start > 1 {
+			start--
+		}
+	}
+	return start
+}
+
+var parenthesesRe = regexp.MustCompile(`\([^)]*\)?`)
+
+// words returns the normalized words of name.
+func words(name string) []string {
+	title := cases.Title(language.LatinAmericanSpanish)
+	name = parenthesesRe.ReplaceAllString(norm.NFC.String(name), " ")
+	name = strings.TrimFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r)
+	})
+	ws := strings.Fields(name)
+	for i, w := range ws {
+		if lower := strings.ToLower(w); particles[lower] && i > 0 {
+			ws[i] = lower
+		} else {
+			ws[i] = title.String(w)
+		}
+	}
+	return ws
+}
@@ -0,0 +1,53 @@
+package names
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name   string
+		wanted Name
+	}{
+		{"KAROL ANELIT GUZMAN PALMA", Name{"Karol Anelit", "Guzman", "Palma"}},
+		{"yeimi yuriko de la cruz olvera", Name{"Yeimi Yuriko", "de la Cruz", "Olvera"}},
+		{"MARÍA DE LA LUZ PÉREZ DE LA CRUZ", Name{"María de la Luz", "Pérez", "de la Cruz"}},
+		{"Oscar Del Castillo Martinez", Name{"Oscar", "del Castillo", "Martinez"}},
+		{"Jose De Jesus Benitez", Name{"Jose de Jesus", "Benitez", ""}},
+		{"María del Carmen", Name{"María del Carmen", "", ""}},
+		{"José Ortega y Gasset", Name{"José", "Ortega y Gasset", ""}},
+		{"Juan  Pérez", Name{"Juan", "Pérez", ""}},
+		{"Valeria", Name{"Valeria", "", ""}},
+		{"Natalia González Martínez.", Name{"Natalia", "González", "Martínez"}},
+		{"Carlos Manuel Bacilio Juárez.", Name{"Carlos Manuel", "Bacilio", "Juárez"}},
+		{"Maribel García Vivar.", Name{"Maribel", "García", "Vivar"}},
+		{"Mateo Sánchez Arce (Gto)", Name{"Mateo", "Sánchez", "Arce"}},
+		{"Luis Manuel Ugalde Velarde (Sin.)", Name{"Luis Manuel", "Ugalde", "Velarde"}},
+		{"", Name{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Parse(tc.name); got != tc.wanted {
+				t.Errorf("got %#v; want %#v", got, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		name   string
+		wanted string
+	}{
+		{"JUAN ROMAN MONROY DE LA ROSA", "Juan Roman Monroy de la Rosa"},
+		{"de los Santos", "De los Santos"},
+		// The decomposed accents are composed.
+		{"Jose\u0301 LO\u0301PEZ", "Jos\u00e9 L\u00f3pez"},
+		{"maría-josé  pérez", "María-José Pérez"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Normalize(tc.name); got != tc.wanted {
+				t.Errorf("got %q; want %q", got, tc.wanted)
+			}
+		})
+	}
+}
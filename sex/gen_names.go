@@ -0,0 +1,99 @@
+//go:build ignore
+
+// gen_names writes names.txt from the counts of people by given name and
+// sex, e.g. the ones of the RENAPO (Registro Nacional de Población) that
+// back the INEGI "Nombres en México" app. The counts are a CSV file with
+// the columns name, women and men, and a header:
+//
+//	go run gen_names.go -counts nombres.csv -source "RENAPO, 2023" > names.txt
+//
+// Infer looks up the first given name found in names.txt, so the people
+// are counted by the first word of their given names: "María José" counts
+// for "maria".
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/midir99/rastreadora/internal/fold"
+)
+
+type counts struct {
+	women int
+	men   int
+}
+
+func main() {
+	countsPath := flag.String("counts", "", "the CSV `file` with the columns name, women and men")
+	source := flag.String("source", "", "the `dataset` the counts come from, written in the header")
+	minPeople := flag.Int("min", 1000, "skip the names of fewer `people`")
+	flag.Parse()
+	if *countsPath == "" || *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*countsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	names, err := readCounts(f)
+	if err != nil {
+		log.Fatalf("%s: %s", *countsPath, err)
+	}
+	fmt.Println("# Given names in Mexico and the share of women among the people who have")
+	fmt.Println("# them, written in lower case and without accents. Generated by")
+	fmt.Printf("# gen_names.go from %s, do not edit.\n", *source)
+	keys := make([]string, 0, len(names))
+	for name, c := range names {
+		if c.women+c.men >= *minPeople {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		c := names[name]
+		fmt.Printf("%s %.2f\n", name, float64(c.women)/float64(c.women+c.men))
+	}
+}
+
+func readCounts(r io.Reader) (map[string]counts, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no rows")
+	}
+	names := make(map[string]counts)
+	for i, row := range rows[1:] {
+		if len(row) != 3 {
+			return nil, fmt.Errorf("row %d has %d columns, want 3", i+2, len(row))
+		}
+		women, err := strconv.Atoi(strings.TrimSpace(row[1]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", i+2, err)
+		}
+		men, err := strconv.Atoi(strings.TrimSpace(row[2]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", i+2, err)
+		}
+		words := strings.Fields(fold.Fold(row[0]))
+		if len(words) == 0 {
+			continue
+		}
+		c := names[words[0]]
+		c.women += women
+		c.men += men
+		names[words[0]] = c
+	}
+	return names, nil
+}
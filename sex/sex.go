@@ -0,0 +1,129 @@
+// Package sex infers the sex of a missing person from the given names and
+// from the gendered words of the texts about them, e.g. "desaparecida" or
+// "el menor", for the sources that don't tell it.
+package sex
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/midir99/rastreadora/internal/fold"
+	"github.com/midir99/rastreadora/mpp"
+)
+
+// MinConfidence is the confidence below which Infer gives up.
+const MinConfidence = 0.75
+
+// cueOdds are the odds of a gendered word being right about the sex, every
+// word found multiplies the odds of its sex by them.
+const cueOdds = 19
+
+//go:embed names.txt
+var namesFile string
+
+// femaleShares maps the given names to the share of women among the people
+// who have them, see names.txt and gen_names.go.
+var femaleShares = parseNames(namesFile)
+
+func parseNames(data string) map[string]float64 {
+	shares := make(map[string]float64)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		share, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			panic("sex: invalid share in names.txt: " + line)
+		}
+		shares[fields[0]] = share
+	}
+	return shares
+}
+
+// femaleCues and maleCues are the words and pairs of words that tell the sex
+// of the person a text is about, without accents.
+var (
+	femaleCues = []string{
+		"desaparecida", "localizada", "extraviada",
+		"la menor", "una menor", "la joven", "una joven", "la adolescente",
+		"una adolescente", "nina", "senora", "senorita", "mujer", "hija",
+	}
+	maleCues = []string{
+		"desaparecido", "localizado", "extraviado",
+		"el menor", "del menor", "un menor", "el joven", "del joven", "un joven",
+		"el adolescente", "del adolescente", "un adolescente", "nino", "senor",
+		"hombre", "hijo",
+	}
+)
+
+// Guess is an inferred sex.
+type Guess struct {
+	Sex mpp.Sex
+	// Confidence is the probability that Sex is right, from 0.5 to 1.
+	Confidence float64
+}
+
+// Infer guesses the sex from the given names and the texts about the person,
+// e.g. the title of a post. The evidence of the first given name found in
+// the dictionary (so "María José" is a woman and "José María" a man) and of
+// every gendered word of the texts is added up. It returns false if there
+// is no evidence or if the confidence is below MinConfidence.
+func Infer(givenNames string, texts ...string) (Guess, bool) {
+	logit := nameLogit(givenNames)
+	for _, text := range texts {
+		logit += textLogit(text)
+	}
+	pFemale := 1 / (1 + math.Exp(-logit))
+	guess := Guess{Sex: mpp.SexFemale, Confidence: pFemale}
+	if pFemale < 0.5 {
+		guess = Guess{Sex: mpp.SexMale, Confidence: 1 - pFemale}
+	}
+	// Round the confidence so that it reads well in the output.
+	guess.Confidence = math.Round(guess.Confidence*100) / 100
+	if logit == 0 || guess.Confidence < MinConfidence {
+		return Guess{}, false
+	}
+	return guess, true
+}
+
+// nameLogit returns the log-odds of a woman given the names.
+func nameLogit(givenNames string) float64 {
+	for _, name := range words(givenNames) {
+		share, ok := femaleShares[name]
+		if !ok {
+			continue
+		}
+		share = math.Min(math.Max(share, 0.01), 0.99)
+		return math.Log(share / (1 - share))
+	}
+	return 0
+}
+
+// textLogit returns the log-odds of a woman given the gendered words of text.
+func textLogit(text string) float64 {
+	ws := words(text)
+	count := func(cues []string) int {
+		n := 0
+		for _, cue := range cues {
+			cueWords := strings.Fields(cue)
+			for i := 0; i+len(cueWords) <= len(ws); i++ {
+				if strings.Join(ws[i:i+len(cueWords)], " ") == cue {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	return float64(count(femaleCues)-count(maleCues)) * math.Log(cueOdds)
+}
+
+// words returns the words of s in lower case and without accents.
+func words(s string) []string {
+	return strings.FieldsFunc(fold.Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
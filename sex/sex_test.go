@@ -0,0 +1,63 @@
+package sex
+
+import (
+	"testing"
+
+	"github.com/midir99/rastreadora/mpp"
+)
+
+func TestInfer(t *testing.T) {
+	testCases := []struct {
+		name             string
+		givenNames       string
+		texts            []string
+		wantedSex        mpp.Sex
+		wantedConfidence float64
+	}{
+		{"female name", "Karla Lissete", nil, mpp.SexFemale, 0.99},
+		{"male name", "Luis Adrián", nil, mpp.SexMale, 0.99},
+		{"first name wins", "María José", nil, mpp.SexFemale, 0.99},
+		{"first known name", "Xóchitl Guadalupe", nil, mpp.SexFemale, 0.99},
+		{"abbreviation", "Ma. Guadalupe", nil, mpp.SexFemale, 0.99},
+		{"shared name", "Guadalupe", nil, mpp.SexFemale, 0.8},
+		{"shared name and wording", "Guadalupe", []string{"Localizado el joven Guadalupe"}, mpp.SexMale, 0.99},
+		{"wording", "Karol", []string{"Desaparecida; Karol Díaz"}, mpp.SexFemale, 0.95},
+		{"article", "", []string{"Se busca a la menor"}, mpp.SexFemale, 0.95},
+		{"contraction", "", []string{"Ficha del adolescente"}, mpp.SexMale, 0.95},
+		{"niño", "", []string{"NIÑO EXTRAVIADO"}, mpp.SexMale, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			guess, ok := Infer(tc.givenNames, tc.texts...)
+			if !ok {
+				t.Fatalf("got no guess; want %s", tc.wantedSex)
+			}
+			if guess.Sex != tc.wantedSex {
+				t.Errorf("got %s; want %s", guess.Sex, tc.wantedSex)
+			}
+			if guess.Confidence != tc.wantedConfidence {
+				t.Errorf("got confidence %v; want %v", guess.Confidence, tc.wantedConfidence)
+			}
+		})
+	}
+}
+
+func TestInferGivesUp(t *testing.T) {
+	testCases := []struct {
+		name       string
+		givenNames string
+		texts      []string
+	}{
+		{"unknown name", "Karol", nil},
+		{"no evidence", "", []string{"Ficha de búsqueda"}},
+		{"shared name", "Cruz", nil},
+		{"contradiction", "", []string{"la menor fue localizado"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if guess, ok := Infer(tc.givenNames, tc.texts...); ok {
+				t.Errorf("got %s (%v); want no guess", guess.Sex, guess.Confidence)
+			}
+		})
+	}
+}
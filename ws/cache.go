@@ -0,0 +1,121 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrNotCached is returned in offline mode for the pages missing from the
+// cache.
+var ErrNotCached = errors.New("page not in cache")
+
+type CachePolicy struct {
+	// MaxAge tells for how long a cached page is used without asking the
+	// server whether it changed, nil means it is always revalidated.
+	MaxAge func(u *url.URL) time.Duration
+	// Offline serves every page from the cache and never uses the network.
+	Offline bool
+}
+
+// CacheMaxAger is implemented by the sources whose pages rarely change, so
+// they can be served from the cache without asking the server.
+type CacheMaxAger interface {
+	CacheMaxAge(u *url.URL) time.Duration
+}
+
+type cachingTransport struct {
+	cassette Cassette
+	next     http.RoundTripper
+	policy   CachePolicy
+}
+
+// NewCachingTransport returns a transport that keeps in dir the successful
+// responses obtained through next. Cached pages older than the max age of the
+// policy are revalidated with If-None-Match and If-Modified-Since.
+func NewCachingTransport(dir string, next http.RoundTripper, policy CachePolicy) http.RoundTripper {
+	return &cachingTransport{cassette: Cassette{Dir: dir}, next: next, policy: policy}
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	rawUrl := req.URL.String()
+	// A missing or broken entry, e.g. left by a run older than the atomic
+	// writes, is a miss and is fetched again.
+	cached, body, err := t.cassette.Load(req.Method, rawUrl)
+	if err != nil {
+		cached = nil
+	}
+	if t.policy.Offline {
+		if cached == nil {
+			return nil, fmt.Errorf("%s: %w", rawUrl, ErrNotCached)
+		}
+		return cached.Response(req, body), nil
+	}
+	if cached == nil {
+		return t.store(req)
+	}
+	if t.policy.MaxAge != nil && time.Since(cached.Date) < t.policy.MaxAge(req.URL) {
+		return cached.Response(req, body), nil
+	}
+	revalidation := req.Clone(req.Context())
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		revalidation.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+		revalidation.Header.Set("If-Modified-Since", lastModified)
+	}
+	resp, err := t.next.RoundTrip(revalidation)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		return t.save(req, resp)
+	}
+	resp.Body.Close()
+	for _, validator := range []string{"ETag", "Last-Modified"} {
+		if value := resp.Header.Get(validator); value != "" {
+			cached.Header.Set(validator, value)
+		}
+	}
+	cached.Date = time.Now()
+	if err := t.cassette.Save(*cached, nil); err != nil {
+		return nil, fmt.Errorf("unable to cache %s: %s", rawUrl, err)
+	}
+	return cached.Response(req, body), nil
+}
+
+func (t *cachingTransport) store(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.save(req, resp)
+}
+
+func (t *cachingTransport) save(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := Exchange{
+		Method:     req.Method,
+		Url:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Date:       time.Now(),
+	}
+	if err := t.cassette.Save(e, body); err != nil {
+		return nil, fmt.Errorf("unable to cache %s: %s", e.Url, err)
+	}
+	return e.Response(req, body), nil
+}
@@ -0,0 +1,112 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachingTransport(t *testing.T) {
+	downloads, revalidations := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Write([]byte("<html><body><h2>Martina Bello Morales</h2></body></html>"))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	retrieve := func(cfg ClientConfig, pageUrl string) error {
+		d, err := NewClient(cfg).RetrieveDocument(context.Background(), pageUrl)
+		if err != nil {
+			return err
+		}
+		if got := d.Query("h2").Text(); got != "Martina Bello Morales" {
+			t.Errorf("got %s; want Martina Bello Morales", got)
+		}
+		return nil
+	}
+
+	cfg := DefaultClientConfig()
+	cfg.CacheDir = dir
+	for i := 0; i < 2; i++ {
+		if err := retrieve(cfg, server.URL); err != nil {
+			t.Fatalf("got error %s; want nil", err)
+		}
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("got %d downloads and %d revalidations; want 1 and 1", downloads, revalidations)
+	}
+
+	cfg.CacheMaxAge = func(u *url.URL) time.Duration { return time.Hour }
+	if err := retrieve(cfg, server.URL); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("got %d downloads and %d revalidations; want 1 and 1", downloads, revalidations)
+	}
+
+	cfg = DefaultClientConfig()
+	cfg.CacheDir = dir
+	cfg.Offline = true
+	if err := retrieve(cfg, server.URL); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if err := retrieve(cfg, server.URL+"/other"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("got error %v; want %s", err, ErrNotCached)
+	}
+}
+
+func TestCachingTransportBrokenEntry(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write([]byte("<html><body><h2>Martina Bello Morales</h2></body></html>"))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	cfg := DefaultClientConfig()
+	cfg.CacheDir = dir
+	if _, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("got the temporary file %s; want it renamed", entry.Name())
+		}
+	}
+
+	// A torn entry, as left by an interrupted write.
+	key := exchangeKey(http.MethodGet, server.URL)
+	if err := os.WriteFile(filepath.Join(dir, key+".json"), []byte(`{"method": "GET", "ur`), 0664); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("got error %s; want the page fetched again", err)
+	}
+	if got := d.Query("h2").Text(); got != "Martina Bello Morales" {
+		t.Errorf("got %s; want Martina Bello Morales", got)
+	}
+	if downloads != 2 {
+		t.Errorf("got %d downloads; want 2", downloads)
+	}
+	if _, _, err := (Cassette{Dir: dir}).Load(http.MethodGet, server.URL); err != nil {
+		t.Errorf("got error %s; want the entry repaired", err)
+	}
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Exchange is a recorded HTTP response. It is stored in a cassette directory
+// as a JSON file next to the file that holds the body.
+type Exchange struct {
+	Method     string      `json:"method"`
+	Url        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	// Date is when the response was received.
+	Date time.Time `json:"date"`
+	// BodyFile is the path of the body, relative to the cassette directory
+	// unless it is absolute.
+	BodyFile string `json:"body_file"`
+}
+
+// Cassette is a directory of recorded exchanges, one per method and URL.
+type Cassette struct {
+	Dir string
+}
+
+func exchangeKey(method, rawUrl string) string {
+	sum := sha256.Sum256([]byte(method + " " + rawUrl))
+	host := "unknown"
+	if u, err := url.Parse(rawUrl); err == nil && u.Host != "" {
+		host = strings.ReplaceAll(u.Host, ":", "_")
+	}
+	return host + "-" + hex.EncodeToString(sum[:8])
+}
+
+// Save stores the exchange and its body, replacing any previous recording of
+// the same method and URL. If e.BodyFile is empty the body is written next to
+// the exchange. The files are replaced whole (see writeFile), so an
+// interrupted run leaves the previous recording or the new one.
+func (c Cassette) Save(e Exchange, body []byte) error {
+	if err := os.MkdirAll(c.Dir, 0775); err != nil {
+		return err
+	}
+	key := exchangeKey(e.Method, e.Url)
+	if e.BodyFile == "" {
+		e.BodyFile = key + ".body"
+		if err := writeFile(filepath.Join(c.Dir, e.BodyFile), body, 0664); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(c.Dir, key+".json"), data, 0664)
+}
+
+// writeFile writes data to a temporary file in the directory of name and
+// renames it to name, so that name is never left half written.
+func writeFile(name string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Load returns the recorded exchange of method and URL, the error wraps
+// os.ErrNotExist if there is no such recording.
+func (c Cassette) Load(method, rawUrl string) (*Exchange, []byte, error) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, exchangeKey(method, rawUrl)+".json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("no recorded response for %s %s: %w", method, rawUrl, err)
+	}
+	e := Exchange{}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, nil, fmt.Errorf("invalid recording of %s %s: %s", method, rawUrl, err)
+	}
+	bodyFile := e.BodyFile
+	if !filepath.IsAbs(bodyFile) {
+		bodyFile = filepath.Join(c.Dir, bodyFile)
+	}
+	body, err := os.ReadFile(bodyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid recording of %s %s: %w", method, rawUrl, err)
+	}
+	return &e, body, nil
+}
+
+type recordingTransport struct {
+	cassette Cassette
+	next     http.RoundTripper
+}
+
+// NewRecordingTransport returns a transport that saves in dir every exchange
+// made through next.
+func NewRecordingTransport(dir string, next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{cassette: Cassette{Dir: dir}, next: next}
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := Exchange{
+		Method:     req.Method,
+		Url:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Date:       time.Now(),
+	}
+	if err := t.cassette.Save(e, body); err != nil {
+		return nil, fmt.Errorf("unable to record %s %s: %s", req.Method, req.URL, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+type replayingTransport struct {
+	cassette Cassette
+}
+
+// NewReplayingTransport returns a transport that never touches the network,
+// it answers with the exchanges recorded in dir.
+func NewReplayingTransport(dir string) http.RoundTripper {
+	return &replayingTransport{cassette: Cassette{Dir: dir}}
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e, body, err := t.cassette.Load(req.Method, req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	return e.Response(req, body), nil
+}
+
+// Response rebuilds the recorded response as an answer to req.
+func (e *Exchange) Response(req *http.Request, body []byte) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
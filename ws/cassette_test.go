@@ -0,0 +1,66 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Write([]byte("<html><body><h2>Martina Bello Morales</h2></body></html>"))
+	}))
+	dir := t.TempDir()
+	cfg := DefaultClientConfig()
+	cfg.RecordDir = dir
+	if _, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	server.Close()
+
+	cfg = DefaultClientConfig()
+	cfg.ReplayDir = dir
+	client := NewClient(cfg)
+	d, err := client.RetrieveDocument(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if got := d.Query("h2").Text(); got != "Martina Bello Morales" {
+		t.Errorf("got %s; want Martina Bello Morales", got)
+	}
+	if _, err := client.RetrieveDocument(context.Background(), server.URL+"/other"); err == nil {
+		t.Errorf("got nil error for a request that was not recorded")
+	}
+}
+
+func TestRecordCachedPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.Write([]byte("<html><body><h2>Martina Bello Morales</h2></body></html>"))
+	}))
+	cacheDir, recordDir := t.TempDir(), t.TempDir()
+	cfg := DefaultClientConfig()
+	cfg.CacheDir = cacheDir
+	if _, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	server.Close()
+
+	cfg.Offline = true
+	cfg.RecordDir = recordDir
+	if _, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+
+	cfg = DefaultClientConfig()
+	cfg.ReplayDir = recordDir
+	d, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if got := d.Query("h2").Text(); got != "Martina Bello Morales" {
+		t.Errorf("got %s; want Martina Bello Morales", got)
+	}
+}
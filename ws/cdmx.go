@@ -3,64 +3,31 @@ package ws
 import (
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
-	"time"
 
+	"github.com/midir99/rastreadora/ages"
+	"github.com/midir99/rastreadora/dates"
 	"github.com/midir99/rastreadora/doc"
 	"github.com/midir99/rastreadora/mpp"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
-func ParseCdmxDate(value string) (time.Time, error) {
-	date := strings.Split(strings.ToLower(value), " ")
-	if len(date) != 5 {
-		return time.Time{}, fmt.Errorf("unable to parse date %s", value)
-	}
-	var (
-		Day   = 0
-		Month = 2
-		Year  = 4
-	)
-	day, err := strconv.ParseUint(date[Day], 10, 64)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("unable to parse date %s (invalid day number: %s)", value, date[Day])
-	}
-	var month time.Month
-	switch date[Month] {
-	case "enero":
-		month = time.January
-	case "febrero":
-		month = time.February
-	case "marzo":
-		month = time.March
-	case "abril":
-		month = time.April
-	case "mayo":
-		month = time.May
-	case "junio":
-		month = time.June
-	case "julio":
-		month = time.July
-	case "agosto":
-		month = time.August
-	case "septiembre":
-		month = time.September
-	case "octubre":
-		month = time.October
-	case "noviembre":
-		month = time.November
-	case "diciembre":
-		month = time.December
-	default:
-		return time.Time{}, fmt.Errorf("unable to parse date %s (invalid month: %s)", value, month)
-	}
-	year, err := strconv.ParseUint(date[Year], 10, 64)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("unable to parse date %s (invalid year number: %s)", value, date[Year])
-	}
-	return time.Date(int(year), month, int(day), 0, 0, 0, 0, time.UTC), nil
+func init() {
+	Register(cdmxCustomSource{})
+	doc.RegisterParser("cdmxage", func(value string) (interface{}, error) {
+		return ParseCdmxAge(value)
+	})
+	doc.RegisterParser("cdmxdate", func(value string) (interface{}, error) {
+		return ParseCdmxDate(value)
+	})
+	doc.RegisterParser("cdmxfound", func(value string) (interface{}, error) {
+		return ParseCdmxFound(value), nil
+	})
+}
+
+// ParseCdmxDate parses the dates of the listing of the Ciudad de México, e.g.
+// "29 de julio de 2022".
+func ParseCdmxDate(value string) (dates.Date, error) {
+	return dates.ParseInLocation(value, mpp.StateCiudadDeMexico.Location())
 }
 
 func ParseCdmxFound(value string) bool {
@@ -76,77 +43,101 @@ func ParseCdmxFound(value string) bool {
 	}
 }
 
-func ParseCdmxAge(value string) (int, error) {
-	age := strings.Split(value, " ")
-	years, err := strconv.ParseUint(age[0], 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("unable to parse age %s", value)
-	}
-	return int(years), nil
+// ParseCdmxAge parses the ages of the listing of the Ciudad de México, e.g.
+// "15 Años, 4 Meses" or "8 Meses".
+func ParseCdmxAge(value string) (ages.Age, error) {
+	return ages.Parse(value)
 }
 
 func MakeCdmxCustomUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://personasdesaparecidas.fgjcdmx.gob.mx/listado.php?pa=%d&re=100", pageNum)
 }
 
+// cdmxCustomEntry is the second cell of a row of the CDMX listing: the name
+// followed by "Label: value" lines and the link to the record.
+type cdmxCustomEntry struct {
+	Name        string     `xpath:"text()[1]" required:"true"`
+	PostUrl     *url.URL   `css:"a" attr:"href" abs:"true" required:"true"`
+	Age         string     `label:"Edad"`
+	MissingDate dates.Date `label:"Se Extravio el" parse:"cdmxdate"`
+	Record      string     `label:"Expediente"`
+	Found       bool       `label:"Estatus" parse:"cdmxfound"`
+}
+
+// cdmxListingTable selects the table of the listing, the innermost one with
+// links to the records, so that another table added to the page isn't read
+// instead.
+const cdmxListingTable = `table:has(a[href*="consulta-FIPEDE"]):not(:has(table))`
+
+// The columns of the CDMX listing have no header, they are named by their
+// position.
+const (
+	cdmxPhotoColumn = "1"
+	cdmxDataColumn  = "2"
+)
+
 func ScrapeCdmxCustomAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
-	for i, tr := range d.QueryAll("tbody tr") {
-		tds := tr.QueryAll("td")
-		if len(tds) != 2 {
+	for i, row := range d.Query(cdmxListingTable).Table() {
+		if len(row) != 2 {
 			errs[i+1] = fmt.Errorf("entry only has not 2 td elements")
 			continue
 		}
-		posterTd := tds[0]
-		dataTd := tds[1]
-		mpName := cases.Title(language.LatinAmericanSpanish).String(strings.ReplaceAll(strings.TrimSpace(dataTd.NthChild(0).Text()), "\u00A0", " "))
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
+		entry := cdmxCustomEntry{}
+		if err := unmarshalEntry(row.Get(cdmxDataColumn), &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		postUrl := strings.TrimSpace(dataTd.NthChild(10).AttrOr("href", ""))
-		if postUrl == "" {
-			errs[i+1] = fmt.Errorf("PoPostUrl can't be empty")
-			continue
-		}
-		poPostUrl, err := url.Parse("https://personasdesaparecidas.fgjcdmx.gob.mx/" + postUrl)
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
-			continue
-		}
-		var poPosterUrl *url.URL
-		posterUrl := strings.TrimSpace(posterTd.Query("img").AttrOr("src", ""))
-		if posterUrl != "" {
-			posterUrl = "https://personasdesaparecidas.fgjcdmx.gob.mx/" + posterUrl
-			poPosterUrl, _ = url.Parse(posterUrl)
-		}
-		var missingDate time.Time
-		missingDateLegend := strings.Split(dataTd.NthChild(4).Text(), ":\u00A0")
-		if len(missingDateLegend) == 2 {
-			missingDate, _ = ParseCdmxDate(missingDateLegend[1])
+		poPosterUrl, _ := row.Get(cdmxPhotoColumn).Query("img").AbsURL("src")
+		var cbsLegend string
+		if entry.Record != "" {
+			cbsLegend = "Expediente: " + entry.Record
 		}
-		var found bool
-		foundLegend := strings.Split(dataTd.NthChild(8).Text(), ":\u00A0")
-		if len(foundLegend) == 2 {
-			found = ParseCdmxFound(foundLegend[1])
-		}
-		var age int
-		ageLegend := strings.Split(dataTd.NthChild(2).Text(), ":\u00A0")
-		if len(ageLegend) == 2 {
-			age, _ = ParseCdmxAge(ageLegend[1])
-		}
-		cbsLegend := strings.TrimSpace(strings.ReplaceAll(dataTd.NthChild(6).Text(), "\u00A0", " "))
-		mpps = append(mpps, mpp.MissingPersonPoster{
+		missing := mpp.MissingPersonPoster{
 			CircumstancesBehindDissapearance: cbsLegend,
-			Found:                            found,
-			MissingDate:                      missingDate,
-			MpAgeWhenDisappeared:             age,
-			MpName:                           mpName,
+			Found:                            entry.Found,
 			PoPosterUrl:                      poPosterUrl,
-			PoPostUrl:                        poPostUrl,
+			PoPostUrl:                        entry.PostUrl,
 			PoState:                          mpp.StateCiudadDeMexico,
-		})
+		}
+		missing.SetName(entry.Name)
+		missing.SetMissingDate(entry.MissingDate)
+		// The age is parsed here rather than by a parse tag so that a
+		// missing age isn't taken for an age of 0.
+		if age, err := ParseCdmxAge(entry.Age); entry.Age != "" && err == nil {
+			missing.SetAge(age)
+		}
+		// The status is always in masculine, only the name tells the sex.
+		inferSex(&missing)
+		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
+
+type cdmxCustomSource struct{}
+
+func (cdmxCustomSource) Name() string {
+	return "cdmx-custom"
+}
+
+func (cdmxCustomSource) State() mpp.State {
+	return mpp.StateCiudadDeMexico
+}
+
+func (cdmxCustomSource) AlertType() mpp.AlertType {
+	return mpp.AlertType("")
+}
+
+func (cdmxCustomSource) MakeUrl(pageNum uint64) string {
+	return MakeCdmxCustomUrl(pageNum)
+}
+
+func (cdmxCustomSource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeCdmxCustomAlerts(d)
+}
+
+// The pages past the last one have an empty listing.
+func (cdmxCustomSource) LastPage(d *doc.Doc) bool {
+	return len(d.Query(cdmxListingTable).Table()) == 0
+}
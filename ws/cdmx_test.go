@@ -0,0 +1,109 @@
+package ws
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midir99/rastreadora/doc"
+	"github.com/midir99/rastreadora/mpp"
+	"golang.org/x/net/html"
+)
+
+func loadCdmxPage(t *testing.T) *doc.Doc {
+	t.Helper()
+	pageUrl := MakeCdmxCustomUrl(1)
+	d, err := fileFetcher{pageUrl: "cdmx/custom-alerts-page.html"}.RetrieveDocument(context.Background(), pageUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestScrapeCdmxCustomAlerts(t *testing.T) {
+	mpps, errs := ScrapeCdmxCustomAlerts(loadCdmxPage(t))
+	if len(mpps) != 100 || len(errs) != 0 {
+		t.Fatalf("got %d posters and %d errors; want 100 and 0", len(mpps), len(errs))
+	}
+	testCases := []struct {
+		index  int
+		wanted mpp.MissingPersonPoster
+	}{
+		{0, mpp.MissingPersonPoster{
+			MpName:                           "Karol Anelit Guzman Palma",
+			MpAgeWhenDisappeared:             15,
+			MissingDate:                      time.Date(2022, time.July, 29, 0, 0, 0, 0, mpp.StateCiudadDeMexico.Location()),
+			CircumstancesBehindDissapearance: "Expediente: AYO/2491/2022",
+			Found:                            false,
+		}},
+		{1, mpp.MissingPersonPoster{
+			MpName:                           "Luis Adrian Solis Lara",
+			MpAgeWhenDisappeared:             33,
+			MissingDate:                      time.Date(2022, time.July, 29, 0, 0, 0, 0, mpp.StateCiudadDeMexico.Location()),
+			CircumstancesBehindDissapearance: "Expediente: AYO/2485/2022",
+			Found:                            true,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.wanted.MpName, func(t *testing.T) {
+			got := mpps[tc.index]
+			if got.MpName != tc.wanted.MpName {
+				t.Errorf("got MpName %s; want %s", got.MpName, tc.wanted.MpName)
+			}
+			if got.MpAgeWhenDisappeared != tc.wanted.MpAgeWhenDisappeared || !got.MpAgeStated {
+				t.Errorf("got MpAgeWhenDisappeared %d (stated %t); want %d stated", got.MpAgeWhenDisappeared, got.MpAgeStated, tc.wanted.MpAgeWhenDisappeared)
+			}
+			if !got.MissingDate.Equal(tc.wanted.MissingDate) {
+				t.Errorf("got MissingDate %s; want %s", got.MissingDate, tc.wanted.MissingDate)
+			}
+			if got.CircumstancesBehindDissapearance != tc.wanted.CircumstancesBehindDissapearance {
+				t.Errorf("got CircumstancesBehindDissapearance %s; want %s", got.CircumstancesBehindDissapearance, tc.wanted.CircumstancesBehindDissapearance)
+			}
+			if got.Found != tc.wanted.Found {
+				t.Errorf("got Found %t; want %t", got.Found, tc.wanted.Found)
+			}
+			if got.PoState != mpp.StateCiudadDeMexico {
+				t.Errorf("got PoState %s; want %s", got.PoState, mpp.StateCiudadDeMexico)
+			}
+		})
+	}
+	first := mpps[0]
+	if wanted := "https://personasdesaparecidas.fgjcdmx.gob.mx/PDF/consulta-FIPEDE.php?id=187910"; first.PoPostUrl == nil || first.PoPostUrl.String() != wanted {
+		t.Errorf("got PoPostUrl %v; want %s", first.PoPostUrl, wanted)
+	}
+	if wanted := "https://personasdesaparecidas.fgjcdmx.gob.mx/PDF/fotos/AYO24912022.JPG"; first.PoPosterUrl == nil || first.PoPosterUrl.String() != wanted {
+		t.Errorf("got PoPosterUrl %v; want %s", first.PoPosterUrl, wanted)
+	}
+}
+
+func TestScrapeCdmxCustomAlertsOtherTable(t *testing.T) {
+	d := loadCdmxPage(t)
+	// A table added before the listing, e.g. for the layout of the page.
+	other, err := html.Parse(strings.NewReader("<table><tr><td>Aviso</td><td>Consulte las fichas</td></tr></table>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := doc.New(other, nil).Query("table")
+	table.Parent.RemoveChild(table.Node)
+	body := d.Query("body")
+	body.InsertBefore(table.Node, body.FirstChild)
+
+	mpps, errs := ScrapeCdmxCustomAlerts(d)
+	if len(mpps) != 100 || len(errs) != 0 {
+		t.Errorf("got %d posters and %d errors; want 100 and 0", len(mpps), len(errs))
+	}
+	if (cdmxCustomSource{}).LastPage(d) {
+		t.Errorf("got LastPage true; want false")
+	}
+}
+
+func TestCdmxCustomLastPage(t *testing.T) {
+	node, err := html.Parse(strings.NewReader(`<table class="table"><tbody></tbody></table>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(cdmxCustomSource{}).LastPage(doc.New(node, nil)) {
+		t.Errorf("got LastPage false; want true")
+	}
+}
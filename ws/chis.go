@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/url"
@@ -8,12 +9,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/midir99/rastreadora/dates"
 	"github.com/midir99/rastreadora/doc"
 	"github.com/midir99/rastreadora/mpp"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
+func init() {
+	Register(chisHasVistoASource{})
+	doc.RegisterParser("chisdate", func(value string) (interface{}, error) {
+		return ParseChisDate(value)
+	})
+	doc.RegisterParser("chisfound", func(value string) (interface{}, error) {
+		return ParseChisFound(value), nil
+	})
+	doc.RegisterParser("chisheight", func(value string) (interface{}, error) {
+		return ParseChisHeigth(value)
+	})
+	doc.RegisterParser("chisweight", func(value string) (interface{}, error) {
+		return ParseChisWeight(value)
+	})
+}
+
 func ParseChisBuild(value string) mpp.PhysicalBuild {
 	switch strings.ToLower(value) {
 	case "atletica":
@@ -56,12 +72,10 @@ func ParseChisComplexion(value string) mpp.Complexion {
 	}
 }
 
-func ParseChisDate(value string) (time.Time, error) {
-	date, err := time.Parse("02/01/2006", value)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("unable to parse date %s", value)
-	}
-	return date, nil
+// ParseChisDate parses the dates of the profiles of Chiapas, e.g.
+// "02/04/1981".
+func ParseChisDate(value string) (dates.Date, error) {
+	return dates.ParseInLocation(value, mpp.StateChiapas.Location())
 }
 
 func ParseChisFound(value string) bool {
@@ -122,111 +136,156 @@ func MakeChisHasVistoAUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://www.fge.chiapas.gob.mx/Servicios/Hasvistoa/Page/%d", pageNum)
 }
 
-func ScrapeChisHasVistoAExtraData(pageUrl string) (*mpp.MissingPersonPoster, error) {
-	doc, err := RetrieveDocument(pageUrl, false)
+// chisHasVistoADetails is the profile shown in the page of each entry of
+// the "Has visto a" listing of Chiapas.
+type chisHasVistoADetails struct {
+	Sex                              string     `label:"Sexo"`
+	Height                           int        `label:"Estatura" parse:"chisheight"`
+	Weight                           int        `label:"Peso" parse:"chisweight"`
+	Eyes                             string     `label:"Ojos"`
+	Hair                             string     `label:"Cabello"`
+	MissingDate                      dates.Date `label:"Fecha desaparicion" parse:"chisdate"`
+	Build                            string     `label:"Complexion"`
+	Complexion                       string     `label:"Tez"`
+	Dob                              dates.Date `label:"Fecha de nacimiento" parse:"chisdate"`
+	CircumstancesBehindDissapearance string     `label:"Circunstancia; Circunstancias"`
+	Record                           string     `label:"Registro"`
+	Mouth                            string     `label:"Boca"`
+	NoseSize                         string     `label:"Tamano de nariz"`
+	NoseType                         string     `label:"Tipo de nariz"`
+	SchoolingLevel                   string     `label:"Escolaridad"`
+	From                             string     `label:"Originario de"`
+	IdentifyingCharacteristics       string     `label:"Senas particulares"`
+}
+
+func ScrapeChisHasVistoAExtraData(ctx context.Context, f Fetcher, pageUrl string) (*mpp.MissingPersonPoster, error) {
+	doc, err := f.RetrieveDocument(ctx, pageUrl)
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve the page %s", pageUrl)
-	}
-	var (
-		Sex            = 0
-		Heigth         = 1
-		Complexion     = 2
-		Eyes           = 3
-		Hair           = 4
-		Weight         = 5
-		MissingDate    = 6
-		Build          = 7
-		Mouth          = 8
-		NoseSize       = 9
-		NoseType       = 10
-		SchoolingLevel = 11
-		From           = 12
-	)
-	var (
-		Dob                              = 0
-		IdentifyingCharacteristics       = 1
-		CircumstancesBehindDissapearance = 2
-	)
-	missing := mpp.MissingPersonPoster{}
-	identifyingCharacteristics := []string{
-		"Registro: " + strings.TrimSpace(doc.Query(".proile-rating span").Text()),
-	}
-	if data := doc.QueryAll("p.color-subtitulo-theme1"); len(data) == 13 {
-		missing.MpSex = ParseChisSex(strings.TrimSpace(data[Sex].Text()))
-		if height, err := ParseChisHeigth(strings.TrimSpace(data[Heigth].Text())); err == nil {
-			missing.MpHeight = height
-		}
-		if weigth, err := ParseChisWeight(strings.TrimSpace(data[Weight].Text())); err == nil {
-			missing.MpWeight = weigth
-		}
-		missing.MpEyesDescription = strings.TrimSpace(data[Eyes].Text())
-		missing.MpHairDescription = strings.TrimSpace(data[Hair].Text())
-		if md, err := ParseChisDate(strings.TrimSpace(data[MissingDate].Text())); err == nil {
-			missing.MissingDate = md
+		return nil, fmt.Errorf("unable to retrieve the page %s: %w", pageUrl, err)
+	}
+	details := chisHasVistoADetails{}
+	if err := unmarshalEntry(doc.Query(".emp-profile"), &details); err != nil {
+		return nil, err
+	}
+	identifyingCharacteristics := []string{}
+	for _, c := range []struct{ label, value string }{
+		{"Registro", details.Record},
+		{"Boca", details.Mouth},
+		{"Tama\u00F1o de nariz", details.NoseSize},
+		{"Tipo de nariz", details.NoseType},
+		{"Escolaridad", details.SchoolingLevel},
+		{"Originario de", details.From},
+		{"Se\u00F1as particulares", details.IdentifyingCharacteristics},
+	} {
+		if c.value != "" {
+			identifyingCharacteristics = append(identifyingCharacteristics, c.label+": "+c.value)
 		}
-		missing.MpPhysicalBuild = ParseChisBuild(strings.TrimSpace(data[Build].Text()))
-		missing.MpComplexion = ParseChisComplexion(strings.TrimSpace(data[Complexion].Text()))
-		identifyingCharacteristics = append(identifyingCharacteristics, []string{
-			"Boca: " + strings.TrimSpace(data[Mouth].Text()),
-			"Tama\u00F1o de nariz: " + strings.TrimSpace(data[NoseSize].Text()),
-			"Tipo de nariz: " + strings.TrimSpace(data[NoseType].Text()),
-			"Escolaridad: " + strings.TrimSpace(data[SchoolingLevel].Text()),
-			"Originario de: " + strings.TrimSpace(data[From].Text()),
-		}...)
-	}
-	if moreData := doc.QueryAll(".profile-work p"); len(moreData) == 3 {
-		missing.MpDob, _ = ParseChisDate(strings.TrimSpace(moreData[Dob].Text()))
-		identifyingCharacteristics = append(identifyingCharacteristics, "Se\u00F1as particulares: "+strings.TrimSpace(moreData[IdentifyingCharacteristics].Text()))
-		missing.CircumstancesBehindDissapearance = strings.TrimSpace(moreData[CircumstancesBehindDissapearance].Text())
-	}
-	missing.MpIdentifyingCharacteristics = strings.Join(identifyingCharacteristics, ", ")
+	}
+	missing := mpp.MissingPersonPoster{
+		CircumstancesBehindDissapearance: details.CircumstancesBehindDissapearance,
+		MpComplexion:                     ParseChisComplexion(details.Complexion),
+		MpEyesDescription:                details.Eyes,
+		MpHairDescription:                details.Hair,
+		MpHeight:                         details.Height,
+		MpIdentifyingCharacteristics:     strings.Join(identifyingCharacteristics, ", "),
+		MpPhysicalBuild:                  ParseChisBuild(details.Build),
+		MpSex:                            ParseChisSex(details.Sex),
+		MpWeight:                         details.Weight,
+	}
+	missing.SetMissingDate(details.MissingDate)
+	missing.SetDob(details.Dob)
 	return &missing, nil
 }
 
+type chisHasVistoAEntry struct {
+	Name      string   `css:".nombre" required:"true"`
+	PostUrl   *url.URL `css:".nombre" attr:"href" abs:"true" required:"true"`
+	PosterUrl *url.URL `css:".contenido-img img" attr:"src" abs:"true"`
+	Found     bool     `css:"span" parse:"chisfound"`
+}
+
 func ScrapeChisHasVistoAAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
 	for i, div := range d.QueryAll(".column_hasvistoa") {
-		a := div.Query(".nombre")
-		mpName := cases.Title(language.LatinAmericanSpanish).String(strings.TrimSpace(a.Text()))
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
-			continue
-		}
-		postUrl := a.AttrOr("href", "")
-		if postUrl == "" {
-			errs[i+1] = fmt.Errorf("PoPostUrl can't be empty")
+		entry := chisHasVistoAEntry{}
+		if err := unmarshalEntry(div, &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		poPostUrl, err := url.Parse("https://www.fge.chiapas.gob.mx" + postUrl)
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
-			continue
-		}
-		poPosterUrl, _ := url.Parse(div.Query(".contenido-img img").AttrOr("src", ""))
-		found := ParseChisFound(strings.TrimSpace(div.Query("span").Text()))
 		missing := mpp.MissingPersonPoster{
 			AlertType:   mpp.AlertTypeHasVistoA,
-			Found:       found,
-			MpName:      mpName,
-			PoPosterUrl: poPosterUrl,
-			PoPostUrl:   poPostUrl,
+			Found:       entry.Found,
+			PoPosterUrl: entry.PosterUrl,
+			PoPostUrl:   entry.PostUrl,
 			PoState:     mpp.StateChiapas,
 		}
-		if mppData, err := ScrapeChisHasVistoAExtraData(poPostUrl.String()); err == nil {
-			missing.CircumstancesBehindDissapearance = mppData.CircumstancesBehindDissapearance
-			missing.MissingDate = mppData.MissingDate
-			missing.MpComplexion = mppData.MpComplexion
-			missing.MpDob = mppData.MpDob
-			missing.MpEyesDescription = mppData.MpEyesDescription
-			missing.MpHairDescription = mppData.MpHairDescription
-			missing.MpHeight = mppData.MpHeight
-			missing.MpIdentifyingCharacteristics = mppData.MpIdentifyingCharacteristics
-			missing.MpPhysicalBuild = mppData.MpPhysicalBuild
-			missing.MpSex = mppData.MpSex
-			missing.MpWeight = mppData.MpWeight
-		}
+		missing.SetName(entry.Name)
+		// The status is "Desaparecida" (persona) for everyone, only the name
+		// tells the sex.
+		inferSex(&missing)
 		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
+
+type chisHasVistoASource struct{}
+
+func (chisHasVistoASource) Name() string {
+	return "chis-hasvistoa"
+}
+
+func (chisHasVistoASource) State() mpp.State {
+	return mpp.StateChiapas
+}
+
+func (chisHasVistoASource) AlertType() mpp.AlertType {
+	return mpp.AlertTypeHasVistoA
+}
+
+func (chisHasVistoASource) MakeUrl(pageNum uint64) string {
+	return MakeChisHasVistoAUrl(pageNum)
+}
+
+func (chisHasVistoASource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeChisHasVistoAAlerts(d)
+}
+
+func (chisHasVistoASource) LastPage(d *doc.Doc) bool {
+	return len(d.QueryAll(".column_hasvistoa")) == 0
+}
+
+func (chisHasVistoASource) Enrich(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error {
+	if m.PoPostUrl == nil {
+		return fmt.Errorf("PoPostUrl can't be empty")
+	}
+	mppData, err := ScrapeChisHasVistoAExtraData(ctx, f, m.PoPostUrl.String())
+	if err != nil {
+		return err
+	}
+	m.CircumstancesBehindDissapearance = mppData.CircumstancesBehindDissapearance
+	m.MissingDate, m.MissingDatePrecision = mppData.MissingDate, mppData.MissingDatePrecision
+	m.MpComplexion = mppData.MpComplexion
+	m.MpDob, m.MpDobPrecision = mppData.MpDob, mppData.MpDobPrecision
+	m.MpEyesDescription = mppData.MpEyesDescription
+	m.MpHairDescription = mppData.MpHairDescription
+	m.MpHeight = mppData.MpHeight
+	m.MpIdentifyingCharacteristics = mppData.MpIdentifyingCharacteristics
+	m.MpPhysicalBuild = mppData.MpPhysicalBuild
+	if mppData.MpSex != "" {
+		m.MpSex, m.MpSexInferred, m.MpSexConfidence = mppData.MpSex, false, 0
+	} else {
+		inferSex(m, m.CircumstancesBehindDissapearance)
+	}
+	m.MpWeight = mppData.MpWeight
+	deriveAge(m)
+	return nil
+}
+
+// The profiles of the people hardly ever change, unlike the listing pages.
+func (chisHasVistoASource) CacheMaxAge(u *url.URL) time.Duration {
+	if strings.Contains(u.Path, "/Hasvistoa/HASVISTOA/") {
+		return 30 * 24 * time.Hour
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package ws
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/midir99/rastreadora/mpp"
+)
+
+func TestChisHasVistoAEnrich(t *testing.T) {
+	postUrl := "https://www.fge.chiapas.gob.mx/Servicios/Hasvistoa/HASVISTOA/464843DF-773C-486E-89EF-A3DCBE988A65"
+	fetcher := fileFetcher{postUrl: "chis/hva-alert-single.html"}
+	poPostUrl, _ := url.Parse(postUrl)
+	missing := mpp.MissingPersonPoster{PoPostUrl: poPostUrl}
+	if err := (chisHasVistoASource{}).Enrich(context.Background(), fetcher, &missing); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if missing.MpSex != mpp.SexMale {
+		t.Errorf("got MpSex %s; want %s", missing.MpSex, mpp.SexMale)
+	}
+	if missing.MpHeight != 170 {
+		t.Errorf("got MpHeight %d; want 170", missing.MpHeight)
+	}
+	if missing.MpWeight != 68 {
+		t.Errorf("got MpWeight %d; want 68", missing.MpWeight)
+	}
+	if wanted := time.Date(2006, time.March, 4, 0, 0, 0, 0, mpp.StateChiapas.Location()); !missing.MissingDate.Equal(wanted) {
+		t.Errorf("got MissingDate %s; want %s", missing.MissingDate, wanted)
+	}
+	if wanted := time.Date(1981, time.April, 2, 0, 0, 0, 0, mpp.StateChiapas.Location()); !missing.MpDob.Equal(wanted) {
+		t.Errorf("got MpDob %s; want %s", missing.MpDob, wanted)
+	}
+	// The age isn't in the profile, it is derived from the dates.
+	if missing.MpAgeWhenDisappeared != 24 {
+		t.Errorf("got MpAgeWhenDisappeared %d; want 24", missing.MpAgeWhenDisappeared)
+	}
+
+	otherUrl, _ := url.Parse("https://www.fge.chiapas.gob.mx/Servicios/Hasvistoa/HASVISTOA/0")
+	missing = mpp.MissingPersonPoster{PoPostUrl: otherUrl}
+	if err := (chisHasVistoASource{}).Enrich(context.Background(), fetcher, &missing); err == nil {
+		t.Errorf("got nil error for a page that can't be retrieved")
+	}
+}
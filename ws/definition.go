@@ -0,0 +1,531 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/midir99/rastreadora/ages"
+	"github.com/midir99/rastreadora/dates"
+	"github.com/midir99/rastreadora/doc"
+	"github.com/midir99/rastreadora/mpp"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceDefinition describes a source in a YAML or JSON file instead of Go,
+// for the fiscalías that publish a listing of cards like the ones of Guerrero
+// and Morelos. For example:
+//
+//	name: mor-cedulas
+//	state: MX-MOR
+//	url: https://fiscaliamorelos.gob.mx/cedulas/{page}/
+//	entries: article
+//	next_page: a.next.page-numbers
+//	fields:
+//	  name: {css: h3 a, required: true}
+//	  post_url: {css: h3 a, attr: href, required: true}
+//	  publication_date: {css: span, parse: mordate}
+//	  sex: {css: h3 a, values: {desaparecida: F, desaparecido: M}}
+//
+// See FieldDefinition for the fields and the data they can fill in.
+type SourceDefinition struct {
+	// Name is the identifier used in the command line, e.g. "gro-alba".
+	Name      string        `yaml:"name" json:"name"`
+	State     mpp.State     `yaml:"state" json:"state"`
+	AlertType mpp.AlertType `yaml:"alert_type" json:"alert_type"`
+	// Url is the URL of the listing pages, {page} stands for the number of
+	// the page.
+	Url string `yaml:"url" json:"url"`
+	// Entries is the CSS selector of the entries of a listing page.
+	Entries string `yaml:"entries" json:"entries"`
+	// NextPage is the CSS selector of the link to the next page, a page
+	// without it is the last one. If empty the last page is only detected
+	// by the usual signs, e.g. an empty listing.
+	NextPage string `yaml:"next_page" json:"next_page"`
+	// Fields tells where the data of the poster is found in an entry, by
+	// name: name (the only one needed), post_url, poster_url,
+	// publication_date, missing_date, dob, age, sex, found and
+	// circumstances. The ages are read with ages.Parse unless they have a
+	// parser; without an age, it is derived from dob and missing_date.
+	Fields map[string]FieldDefinition `yaml:"fields" json:"fields"`
+}
+
+// FieldDefinition tells where the value of a field is found in an entry,
+// like the tags of doc.Unmarshal do. The URLs are always resolved against the
+// URL of the page.
+type FieldDefinition struct {
+	Css   string `yaml:"css" json:"css"`
+	Xpath string `yaml:"xpath" json:"xpath"`
+	Label string `yaml:"label" json:"label"`
+	Attr  string `yaml:"attr" json:"attr"`
+	// Parse is the name of a parser registered with doc.RegisterParser,
+	// e.g. "mordate" or "wpfullsize".
+	Parse string `yaml:"parse" json:"parse"`
+	// Layout is the layout of a date, e.g. "02/01/2006". Without a layout
+	// or a parser the dates are read with dates.Parse. Either way they are
+	// in the zone of the state.
+	Layout string `yaml:"layout" json:"layout"`
+	// Values is the vocabulary of the field: it maps the texts found in the
+	// entries to the values of the field, e.g. "localizada" to "true" for
+	// found or "desaparecido" to "M" for sex. The texts are compared like
+	// labels (see doc.NormalizeLabel); a text that is not in the vocabulary
+	// takes the value of the longest word of it that it contains, or none.
+	Values map[string]string `yaml:"values" json:"values"`
+	// Value is the value of the field in every entry, e.g. "F" for the sex
+	// of the Alba alerts.
+	Value    string `yaml:"value" json:"value"`
+	Required bool   `yaml:"required" json:"required"`
+}
+
+// definitionTarget is a piece of data of a poster that a FieldDefinition can
+// fill in. parse converts the values of the vocabularies and the fixed values,
+// it is nil for the data that can't have them.
+type definitionTarget struct {
+	field string
+	typ   reflect.Type
+	parse func(value string) (interface{}, error)
+	set   func(m *mpp.MissingPersonPoster, value interface{})
+}
+
+var (
+	stringType = reflect.TypeOf("")
+	urlType    = reflect.TypeOf((*url.URL)(nil))
+	dateType   = reflect.TypeOf(dates.Date{})
+	ageType    = reflect.TypeOf(ages.Age{})
+)
+
+func parseString(value string) (interface{}, error) {
+	return value, nil
+}
+
+var definitionTargets = map[string]definitionTarget{
+	"name": {"Name", stringType, parseString, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.SetName(value.(string))
+	}},
+	"post_url": {"PostUrl", urlType, nil, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.PoPostUrl = value.(*url.URL)
+	}},
+	"poster_url": {"PosterUrl", urlType, nil, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.PoPosterUrl = value.(*url.URL)
+	}},
+	"publication_date": {"PublicationDate", dateType, nil, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.SetPostPublicationDate(value.(dates.Date))
+	}},
+	"missing_date": {"MissingDate", dateType, nil, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.SetMissingDate(value.(dates.Date))
+	}},
+	"dob": {"Dob", dateType, nil, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.SetDob(value.(dates.Date))
+	}},
+	"age": {"Age", ageType, func(value string) (interface{}, error) {
+		return ages.Parse(value)
+	}, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.SetAge(value.(ages.Age))
+	}},
+	"sex": {"Sex", reflect.TypeOf(mpp.Sex("")), func(value string) (interface{}, error) {
+		switch sex := mpp.Sex(strings.ToUpper(value)); sex {
+		case mpp.SexFemale, mpp.SexMale:
+			return sex, nil
+		default:
+			return nil, fmt.Errorf("%q is not a sex, use %s or %s", value, mpp.SexFemale, mpp.SexMale)
+		}
+	}, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.MpSex = value.(mpp.Sex)
+	}},
+	"found": {"Found", reflect.TypeOf(false), func(value string) (interface{}, error) {
+		return strconv.ParseBool(value)
+	}, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.Found = value.(bool)
+	}},
+	"circumstances": {"Circumstances", stringType, parseString, func(m *mpp.MissingPersonPoster, value interface{}) {
+		m.CircumstancesBehindDissapearance = value.(string)
+	}},
+}
+
+// ReadSourceDefinition reads the definition of a source from a .yaml, .yml
+// or .json file. The unknown keys are an error, so that a typo isn't
+// silently ignored.
+func ReadSourceDefinition(filename string) (*SourceDefinition, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	def := SourceDefinition{}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&def)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&def)
+	default:
+		return nil, fmt.Errorf("%s is not a YAML or JSON file", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %s", filename, err)
+	}
+	return &def, nil
+}
+
+// definedSource is a Source described by a SourceDefinition. Its entries are
+// read with doc.Unmarshal into a struct built from the definition.
+type definedSource struct {
+	def       SourceDefinition
+	entryType reflect.Type
+	// fields are read from every entry, fixed holds the values of the
+	// targets that are the same in every entry.
+	fields []definedField
+	fixed  map[string]interface{}
+}
+
+type definedField struct {
+	target   definitionTarget
+	values   map[string]string
+	required bool
+	// localDate tells that the field is a date read as text and parsed in
+	// the zone of the state, with layout or else with dates.Parse.
+	localDate bool
+	layout    string
+	// parseText tells that the field is read as text and parsed with the
+	// parse function of its target, e.g. the ages with ages.Parse and the
+	// sexes, which must be F or M.
+	parseText bool
+}
+
+// NewDefinedSource checks def and returns the Source it describes.
+func NewDefinedSource(def SourceDefinition) (Source, error) {
+	if def.Name == "" {
+		return nil, errors.New("the name can't be empty")
+	}
+	if !def.State.Known() {
+		return nil, fmt.Errorf("%q is not a state, e.g. MX-GRO", def.State)
+	}
+	switch def.AlertType {
+	case "", mpp.AlertTypeAlba, mpp.AlertTypeAmber, mpp.AlertTypeHasVistoA, mpp.AlertTypeOdisea:
+	default:
+		return nil, fmt.Errorf("%q is not an alert type, use %s, %s, %s or %s", def.AlertType, mpp.AlertTypeAlba, mpp.AlertTypeAmber, mpp.AlertTypeHasVistoA, mpp.AlertTypeOdisea)
+	}
+	if !strings.Contains(def.Url, "{page}") {
+		return nil, fmt.Errorf("the url %q has no {page}", def.Url)
+	}
+	if u, err := url.Parse(strings.ReplaceAll(def.Url, "{page}", "1")); err != nil || !u.IsAbs() {
+		return nil, fmt.Errorf("the url %q is not an absolute URL", def.Url)
+	}
+	if def.Entries == "" {
+		return nil, errors.New("the entries selector can't be empty")
+	}
+	if err := checkSelectors(def.Entries, ""); err != nil {
+		return nil, fmt.Errorf("entries: %s", err)
+	}
+	if err := checkSelectors(def.NextPage, ""); err != nil {
+		return nil, fmt.Errorf("next_page: %s", err)
+	}
+	if _, ok := def.Fields["name"]; !ok {
+		return nil, errors.New("the name field is missing")
+	}
+	s := &definedSource{def: def, fixed: make(map[string]interface{})}
+	names := make([]string, 0, len(def.Fields))
+	for name := range def.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	structFields := []reflect.StructField{}
+	for _, name := range names {
+		field, err := s.compileField(name, def.Fields[name])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", name, err)
+		}
+		if field != nil {
+			structFields = append(structFields, *field)
+		}
+	}
+	s.entryType = reflect.StructOf(structFields)
+	return s, nil
+}
+
+// compileField checks the definition of a field and returns the field of the
+// entry struct it needs, or nil for the fixed values.
+func (s *definedSource) compileField(name string, f FieldDefinition) (*reflect.StructField, error) {
+	target, ok := definitionTargets[name]
+	if !ok {
+		return nil, errors.New("unknown field")
+	}
+	if (f.Value != "" || len(f.Values) != 0) && target.parse == nil {
+		return nil, errors.New("it can't have a value or values")
+	}
+	if f.Value != "" {
+		value, err := target.parse(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		s.fixed[name] = value
+		return nil, nil
+	}
+	if f.Css == "" && f.Xpath == "" && f.Label == "" && f.Attr == "" {
+		return nil, errors.New("it needs a css, xpath, label, attr or value")
+	}
+	if err := checkSelectors(f.Css, f.Xpath); err != nil {
+		return nil, err
+	}
+	if _, ok := doc.LookupParser(f.Parse); f.Parse != "" && !ok {
+		return nil, fmt.Errorf("unknown parser %q", f.Parse)
+	}
+	if f.Layout != "" && target.typ != dateType {
+		return nil, errors.New("only dates can have a layout")
+	}
+	values := make(map[string]string, len(f.Values))
+	for text, value := range f.Values {
+		if _, err := target.parse(value); err != nil {
+			return nil, fmt.Errorf("values: %s", err)
+		}
+		values[doc.NormalizeLabel(text)] = value
+	}
+	field := definedField{
+		target:    target,
+		values:    values,
+		required:  f.Required,
+		localDate: target.typ == dateType && f.Parse == "",
+		layout:    f.Layout,
+		parseText: target.parse != nil && target.typ != stringType && f.Parse == "" && len(values) == 0,
+	}
+	tags := []string{}
+	for _, tag := range [][2]string{
+		{"css", f.Css},
+		{"xpath", f.Xpath},
+		{"label", f.Label},
+		{"attr", f.Attr},
+		{"parse", f.Parse},
+	} {
+		if tag[1] != "" {
+			tags = append(tags, tag[0]+":"+strconv.Quote(tag[1]))
+		}
+	}
+	if target.typ == urlType {
+		tags = append(tags, `abs:"true"`)
+	}
+	if f.Required {
+		tags = append(tags, `required:"true"`)
+	}
+	typ := target.typ
+	if len(values) != 0 || field.localDate || field.parseText {
+		typ = stringType
+	}
+	s.fields = append(s.fields, field)
+	return &reflect.StructField{
+		Name: target.field,
+		Type: typ,
+		Tag:  reflect.StructTag(strings.Join(tags, " ")),
+	}, nil
+}
+
+// checkSelectors reports the invalid CSS selectors (separated by ";") and
+// XPath expressions by trying them on an empty document.
+func checkSelectors(css, xpath string) error {
+	empty := &doc.Doc{Node: &html.Node{Type: html.DocumentNode}}
+	if css != "" {
+		for _, query := range strings.Split(css, ";") {
+			if _, err := empty.QueryE(strings.TrimSpace(query)); !errors.Is(err, doc.ErrNoMatch) {
+				return err
+			}
+		}
+	}
+	if xpath != "" {
+		if _, err := empty.XPathE(xpath); !errors.Is(err, doc.ErrNoMatch) {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookUp returns the value of text in the vocabulary values: the value of
+// text itself or else the one of the longest key contained in text.
+func lookUp(values map[string]string, text string) (string, bool) {
+	text = doc.NormalizeLabel(text)
+	if value, ok := values[text]; ok {
+		return value, true
+	}
+	longest := ""
+	for key := range values {
+		if key == "" || !strings.Contains(text, key) {
+			continue
+		}
+		// The ties are broken alphabetically, so the result doesn't depend
+		// on the order of the map.
+		if n, m := utf8.RuneCountInString(key), utf8.RuneCountInString(longest); n > m || n == m && key < longest {
+			longest = key
+		}
+	}
+	if longest == "" {
+		return "", false
+	}
+	return values[longest], true
+}
+
+// parseLocalDate parses a date of the zone loc with layout, or with
+// dates.ParseInLocation if layout is empty.
+func parseLocalDate(value, layout string, loc *time.Location) (dates.Date, error) {
+	if layout == "" {
+		return dates.ParseInLocation(value, loc)
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return dates.Date{}, err
+	}
+	return dates.Date{Time: t, Precision: layoutPrecision(layout)}, nil
+}
+
+// layoutPrecision tells which parts of a date a layout has, by the parts of
+// a time that change what it formats.
+func layoutPrecision(layout string) dates.Precision {
+	t := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	changes := func(other time.Time) bool {
+		return t.Format(layout) != other.Format(layout)
+	}
+	switch {
+	case changes(t.Add(time.Hour)) || changes(t.Add(time.Minute)):
+		return dates.PrecisionTime
+	case changes(t.AddDate(0, 0, 1)):
+		return dates.PrecisionDay
+	case changes(t.AddDate(0, 1, 0)):
+		return dates.PrecisionMonth
+	default:
+		return dates.PrecisionYear
+	}
+}
+
+func (s *definedSource) Name() string {
+	return s.def.Name
+}
+
+func (s *definedSource) State() mpp.State {
+	return s.def.State
+}
+
+func (s *definedSource) AlertType() mpp.AlertType {
+	return s.def.AlertType
+}
+
+func (s *definedSource) MakeUrl(pageNum uint64) string {
+	return strings.ReplaceAll(s.def.Url, "{page}", strconv.FormatUint(pageNum, 10))
+}
+
+func (s *definedSource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	mpps := []mpp.MissingPersonPoster{}
+	errs := make(map[int]error)
+	loc := s.def.State.Location()
+entries:
+	for i, node := range d.QueryAll(s.def.Entries) {
+		entry := reflect.New(s.entryType)
+		if err := unmarshalEntry(node, entry.Interface()); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
+			continue
+		}
+		missing := mpp.MissingPersonPoster{
+			AlertType: s.def.AlertType,
+			PoState:   s.def.State,
+		}
+		for name, value := range s.fixed {
+			definitionTargets[name].set(&missing, value)
+		}
+		for _, field := range s.fields {
+			value := entry.Elem().FieldByName(field.target.field).Interface()
+			if len(field.values) != 0 {
+				text, ok := lookUp(field.values, value.(string))
+				if !ok {
+					continue
+				}
+				// The values were checked by NewDefinedSource.
+				value, _ = field.target.parse(text)
+			}
+			if field.localDate || field.parseText {
+				text := value.(string)
+				if text == "" {
+					continue
+				}
+				var err error
+				if field.localDate {
+					value, err = parseLocalDate(text, field.layout, loc)
+				} else {
+					value, err = field.target.parse(text)
+				}
+				if err != nil && field.required {
+					errs[i+1] = fmt.Errorf("the layout of the entry changed: %s: %s", field.target.field, err)
+					continue entries
+				}
+				if err != nil {
+					continue
+				}
+			}
+			field.target.set(&missing, value)
+		}
+		deriveAge(&missing)
+		inferSex(&missing, node.CleanText())
+		if missing.MpName == "" {
+			errs[i+1] = fmt.Errorf("MpName can't be empty")
+			continue
+		}
+		mpps = append(mpps, missing)
+	}
+	return mpps, errs
+}
+
+func (s *definedSource) LastPage(d *doc.Doc) bool {
+	return s.def.NextPage != "" && len(d.QueryAll(s.def.NextPage)) == 0
+}
+
+// LoadSources reads the definitions of the .yaml, .yml and .json files of
+// dir (see SourceDefinition), registers their sources next to the built-in
+// ones and returns them. Nothing is registered if a definition is invalid or
+// uses the name of another source.
+func LoadSources(dir string) ([]Source, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	loaded := []Source{}
+	names := make(map[string]string)
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+		def, err := ReadSourceDefinition(filename)
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewDefinedSource(*def)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source in %s: %s", filename, err)
+		}
+		if other, dup := names[s.Name()]; dup {
+			return nil, fmt.Errorf("the source %s is defined in %s and %s", s.Name(), other, filename)
+		}
+		if _, dup := Lookup(s.Name()); dup {
+			return nil, fmt.Errorf("the source %s of %s is already registered", s.Name(), filename)
+		}
+		names[s.Name()] = filename
+		loaded = append(loaded, s)
+	}
+	for _, s := range loaded {
+		Register(s)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,222 @@
+package ws
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/midir99/rastreadora/dates"
+	"github.com/midir99/rastreadora/mpp"
+)
+
+func TestDefinedSource(t *testing.T) {
+	def, err := ReadSourceDefinition("testdata/sources/mor-cedulas.yaml")
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	source, err := NewDefinedSource(*def)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if got, want := source.MakeUrl(3), "https://fiscaliamorelos.gob.mx/cedulas/3/"; got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+	d := loadDoc(t, "mor/custom-alerts-page.html")
+	got, gotErrs := source.Scrape(d)
+	want, wantErrs := ScrapeMorCustomAlerts(d)
+	if len(got) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if len(gotErrs) != len(wantErrs) {
+		t.Errorf("got %d errors; want %d", len(gotErrs), len(wantErrs))
+	}
+	if last := source.(Paginator).LastPage(d); last != !hasNextPageLink(d) {
+		t.Errorf("got LastPage %t; want %t", last, !hasNextPageLink(d))
+	}
+}
+
+func TestLoadSources(t *testing.T) {
+	dir := t.TempDir()
+	def := `{
+		"name": "test-gro-localizadas",
+		"state": "MX-GRO",
+		"alert_type": "AL",
+		"url": "https://fiscaliaguerrero.gob.mx/category/alba/page/{page}/",
+		"entries": ".article_content",
+		"fields": {
+			"name": {"css": "h2 a", "required": true},
+			"post_url": {"css": "h2 a", "attr": "href"},
+			"sex": {"value": "F"},
+			"age": {"value": "entre 15 y 17 años"},
+			"found": {"css": "h2 a", "values": {"Localizada": "true", "Localizado": "true", "Desaparecida": "false"}}
+		}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "gro.json"), []byte(def), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a source"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSources(dir)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	t.Cleanup(func() {
+		for _, s := range loaded {
+			unregister(s.Name())
+		}
+	})
+	if len(loaded) != 1 {
+		t.Fatalf("got %d sources; want 1", len(loaded))
+	}
+	source, ok := Lookup("test-gro-localizadas")
+	if !ok {
+		t.Fatalf("the source test-gro-localizadas wasn't registered")
+	}
+	d := loadDoc(t, "gro/alba-alerts-page.html")
+	got, _ := source.Scrape(d)
+	want, _ := ScrapeGroAlbaAlerts(d)
+	if len(got) != len(want) {
+		t.Fatalf("got %d posters; want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Found != want[i].Found || got[i].MpSex != mpp.SexFemale || got[i].AlertType != mpp.AlertTypeAlba ||
+			got[i].MpAgeWhenDisappeared != 16 || !got[i].MpAgeApproximate {
+			t.Errorf("got %v; want %v", got[i], want[i])
+		}
+	}
+	if _, err := LoadSources(dir); err == nil {
+		t.Errorf("got nil error for a source registered twice")
+	}
+}
+
+func TestNewDefinedSourceErrors(t *testing.T) {
+	valid := func() SourceDefinition {
+		return SourceDefinition{
+			Name:    "test-invalid",
+			State:   mpp.StateMorelos,
+			Url:     "https://fiscaliamorelos.gob.mx/cedulas/{page}/",
+			Entries: "article",
+			Fields:  map[string]FieldDefinition{"name": {Css: "h3 a"}},
+		}
+	}
+	testCases := []struct {
+		name   string
+		change func(def *SourceDefinition)
+		wanted string
+	}{
+		{"no page", func(def *SourceDefinition) { def.Url = "https://fiscaliamorelos.gob.mx/cedulas/" }, "{page}"},
+		{"state", func(def *SourceDefinition) { def.State = "Morelos" }, "not a state"},
+		{"unknown state", func(def *SourceDefinition) { def.State = "MX-ZZZ" }, "not a state"},
+		{"alert type", func(def *SourceDefinition) { def.AlertType = "XX" }, "not an alert type"},
+		{"entries", func(def *SourceDefinition) { def.Entries = "article[" }, "entries"},
+		{"no name", func(def *SourceDefinition) { def.Fields = map[string]FieldDefinition{"sex": {Value: "F"}} }, "name field"},
+		{"unknown field", func(def *SourceDefinition) { def.Fields["height"] = FieldDefinition{Css: "p"} }, "unknown field"},
+		{"unknown parser", func(def *SourceDefinition) { def.Fields["dob"] = FieldDefinition{Css: "p", Parse: "nodate"} }, "unknown parser"},
+		{"vocabulary", func(def *SourceDefinition) {
+			def.Fields["sex"] = FieldDefinition{Css: "p", Values: map[string]string{"desaparecida": "mujer"}}
+		}, "not a sex"},
+		{"no selector", func(def *SourceDefinition) { def.Fields["found"] = FieldDefinition{Required: true} }, "needs a css"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			def := valid()
+			tc.change(&def)
+			_, err := NewDefinedSource(def)
+			if err == nil || !strings.Contains(err.Error(), tc.wanted) {
+				t.Errorf("got error %v; want one about %s", err, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestLayoutPrecision(t *testing.T) {
+	testCases := []struct {
+		layout string
+		wanted dates.Precision
+	}{
+		{"2006", dates.PrecisionYear},
+		{"01/2006", dates.PrecisionMonth},
+		{"02/01/2006", dates.PrecisionDay},
+		{"2 Jan 2006", dates.PrecisionDay},
+		{"02/01/2006 15:04", dates.PrecisionTime},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.layout, func(t *testing.T) {
+			if got := layoutPrecision(tc.layout); got != tc.wanted {
+				t.Errorf("got %s; want %s", got, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestLookUp(t *testing.T) {
+	values := map[string]string{"localizada": "true", "no localizada": "false", "desaparecida": "false"}
+	testCases := []struct {
+		text     string
+		wanted   string
+		wantedOk bool
+	}{
+		{"LOCALIZADA", "true", true},
+		{"Localizada: Valeria Benítez", "true", true},
+		{"Aún no localizada", "false", true},
+		{"Desaparecida; Martina Bello", "false", true},
+		{"Ficha de búsqueda", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			got, ok := lookUp(values, tc.text)
+			if got != tc.wanted || ok != tc.wantedOk {
+				t.Errorf("got %s, %t; want %s, %t", got, ok, tc.wanted, tc.wantedOk)
+			}
+		})
+	}
+}
+
+func TestDefinedSourceSex(t *testing.T) {
+	def := SourceDefinition{
+		Name:    "test-gro-sex",
+		State:   mpp.StateGuerrero,
+		Url:     "https://fiscaliaguerrero.gob.mx/category/alba/page/{page}/",
+		Entries: ".article_content",
+		Fields: map[string]FieldDefinition{
+			"name": {Css: "h2 a", Required: true},
+			// The titles, e.g. "Desaparecida; Martina Bello Morales", are
+			// not sexes.
+			"sex": {Css: "h2 a"},
+		},
+	}
+	source, err := NewDefinedSource(def)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	d := loadDoc(t, "gro/alba-alerts-page.html")
+	got, _ := source.Scrape(d)
+	if len(got) == 0 {
+		t.Fatalf("got no posters")
+	}
+	for _, m := range got {
+		if m.MpSex != "" && m.MpSex != mpp.SexFemale && m.MpSex != mpp.SexMale {
+			t.Errorf("got MpSex %q; want %s, %s or none", m.MpSex, mpp.SexFemale, mpp.SexMale)
+		}
+	}
+
+	def.Fields["sex"] = FieldDefinition{Css: "h2 a", Required: true}
+	source, err = NewDefinedSource(def)
+	if err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	if got, errs := source.Scrape(d); len(got) != 0 || len(errs) == 0 {
+		t.Errorf("got %d posters and %d errors; want only errors for a required sex that isn't F or M", len(got), len(errs))
+	}
+}
+
+// unregister removes a source registered by a test, so that the test can run
+// again in the same process.
+func unregister(name string) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	delete(sources, name)
+}
@@ -0,0 +1,100 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/midir99/rastreadora/ages"
+	"github.com/midir99/rastreadora/dates"
+	"github.com/midir99/rastreadora/doc"
+	"github.com/midir99/rastreadora/mpp"
+	"github.com/midir99/rastreadora/sex"
+)
+
+func init() {
+	doc.RegisterParser("wpfullsize", func(value string) (interface{}, error) {
+		return WordPressFullSize(value), nil
+	})
+}
+
+var wordPressSizeRe = regexp.MustCompile(`-\d+x\d+(\.\w+)$`)
+
+// WordPressFullSize returns the URL of the original image of a WordPress
+// thumbnail, e.g. "foto-480x320.jpg" becomes "foto.jpg".
+func WordPressFullSize(imageUrl string) string {
+	return wordPressSizeRe.ReplaceAllString(imageUrl, "$1")
+}
+
+// enrichWithMetadata retrieves the post of m and prefers its metadata (see
+// doc.Metadata) for the poster, the publication date and the URL of the
+// post. What the listing had is kept when the post lacks them.
+func enrichWithMetadata(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error {
+	if m.PoPostUrl == nil {
+		return fmt.Errorf("PoPostUrl can't be empty")
+	}
+	d, err := f.RetrieveDocument(ctx, m.PoPostUrl.String())
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the page %s: %w", m.PoPostUrl, err)
+	}
+	metadata := d.Metadata()
+	if metadata.Image != nil {
+		m.PoPosterUrl = metadata.Image
+	}
+	if !metadata.Published.IsZero() {
+		m.SetPostPublicationDate(dates.Date{Time: metadata.Published.In(m.PoState.Location()), Precision: dates.PrecisionTime})
+	}
+	if metadata.Canonical != nil {
+		m.PoPostUrl = metadata.Canonical
+	}
+	return nil
+}
+
+var wordPressPostRe = regexp.MustCompile(`^/\d{4}/\d{2}/\d{2}/`)
+
+// wordPressPostMaxAge is the CacheMaxAge of the WordPress sources: the posts
+// of the alerts, e.g. "/2022/06/30/david-venancio-venancio/", hardly ever
+// change, unlike the listing pages.
+func wordPressPostMaxAge(u *url.URL) time.Duration {
+	if wordPressPostRe.MatchString(u.Path) {
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// inferSex fills in the sex of m from its given names and the texts about
+// the person (see sex.Infer) unless the source stated it.
+func inferSex(m *mpp.MissingPersonPoster, texts ...string) {
+	if m.MpSex != "" && !m.MpSexInferred {
+		return
+	}
+	if guess, ok := sex.Infer(m.GivenNames, texts...); ok {
+		m.MpSex, m.MpSexInferred, m.MpSexConfidence = guess.Sex, true, guess.Confidence
+	}
+}
+
+// deriveAge fills in the age of m from its date of birth and the date it
+// went missing unless the source stated it.
+func deriveAge(m *mpp.MissingPersonPoster) {
+	if m.MpAgeStated || m.MpDob.IsZero() || m.MissingDate.Before(m.MpDob) {
+		return
+	}
+	months := ages.Between(m.MpDob, m.MissingDate).TotalMonths()
+	m.MpAgeWhenDisappeared, m.MpAgeMonths = months/12, months%12
+	m.MpAgeApproximate, m.MpAgeDerived = false, true
+}
+
+// unmarshalEntry fills in the struct pointed to by v with doc.Unmarshal. Only
+// the errors of the required fields are returned, the optional ones are left
+// empty like the scrapers always did.
+func unmarshalEntry(d *doc.Doc, v interface{}) error {
+	err := doc.Unmarshal(d, v)
+	var fieldErrs doc.FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs.Required()
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/midir99/rastreadora/ages"
+	"github.com/midir99/rastreadora/mpp"
+)
+
+func TestDeriveAge(t *testing.T) {
+	dob := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	missingDate := time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)
+	months, err := ages.Parse("8 meses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name         string
+		age          *ages.Age
+		wanted       int
+		wantedMonths int
+	}{
+		{"no age", nil, 2, 9},
+		// A stated age of 0 years is kept, even if the dates disagree.
+		{"stated months", &months, 0, 8},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := mpp.MissingPersonPoster{MpDob: dob, MissingDate: missingDate}
+			if tc.age != nil {
+				m.SetAge(*tc.age)
+			}
+			deriveAge(&m)
+			if m.MpAgeWhenDisappeared != tc.wanted || m.MpAgeMonths != tc.wantedMonths {
+				t.Errorf("got %d years and %d months; want %d and %d", m.MpAgeWhenDisappeared, m.MpAgeMonths, tc.wanted, tc.wantedMonths)
+			}
+		})
+	}
+}
+
+func TestEnrichWithMetadata(t *testing.T) {
+	listingPoster, _ := url.Parse("https://example.com/listing-480x320.jpg")
+	listingDate := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name            string
+		source          Enricher
+		state           mpp.State
+		postUrl         string
+		filename        string
+		wantedPoster    string
+		wantedDate      time.Time
+		wantedCanonical string
+	}{
+		{
+			"gro-alba",
+			groAlbaSource{},
+			mpp.StateGuerrero,
+			"https://fiscaliaguerrero.gob.mx/2022/04/24/desaparecida-natalia-gonzalez-martinez/",
+			"gro/alba-alert-single.html",
+			"https://fiscaliaguerrero.gob.mx/wp-content/uploads/2022/04/IMG-20220424-WA0005.jpg",
+			time.Date(2022, time.April, 25, 3, 58, 39, 0, time.UTC),
+			"https://fiscaliaguerrero.gob.mx/2022/04/24/desaparecida-natalia-gonzalez-martinez/",
+		},
+		{
+			"mor-custom canonical",
+			morCustomSource{},
+			mpp.StateMorelos,
+			"https://fiscaliamorelos.gob.mx/2022/06/30/david-venancio-venancio-2/",
+			"mor/amber-alert-single.html",
+			"https://fiscaliamorelos.gob.mx/wp-content/uploads/2022/06/66.-AAMOR.68.2022-David-Venancio-14a.jpg",
+			time.Date(2022, time.June, 30, 21, 55, 37, 0, time.UTC),
+			"https://fiscaliamorelos.gob.mx/2022/06/30/david-venancio-venancio/",
+		},
+		{
+			"no metadata",
+			groAmberSource{},
+			mpp.StateGuerrero,
+			"https://fiscaliaguerrero.gob.mx/2022/04/24/desaparecido-sin-metadatos/",
+			"chis/hva-alert-single.html",
+			listingPoster.String(),
+			listingDate,
+			"https://fiscaliaguerrero.gob.mx/2022/04/24/desaparecido-sin-metadatos/",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetcher := fileFetcher{tc.postUrl: tc.filename}
+			postUrl, _ := url.Parse(tc.postUrl)
+			missing := mpp.MissingPersonPoster{
+				PoPosterUrl:           listingPoster,
+				PoPostPublicationDate: listingDate,
+				PoPostUrl:             postUrl,
+				PoState:               tc.state,
+			}
+			if err := tc.source.Enrich(context.Background(), fetcher, &missing); err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if missing.PoPosterUrl.String() != tc.wantedPoster {
+				t.Errorf("got PoPosterUrl %s; want %s", missing.PoPosterUrl, tc.wantedPoster)
+			}
+			if !missing.PoPostPublicationDate.Equal(tc.wantedDate) {
+				t.Errorf("got PoPostPublicationDate %s; want %s", missing.PoPostPublicationDate, tc.wantedDate)
+			}
+			if missing.PoPostUrl.String() != tc.wantedCanonical {
+				t.Errorf("got PoPostUrl %s; want %s", missing.PoPostUrl, tc.wantedCanonical)
+			}
+		})
+	}
+}
+
+func TestWordPressPostMaxAge(t *testing.T) {
+	testCases := []struct {
+		rawUrl string
+		wanted time.Duration
+	}{
+		{"https://fiscaliaguerrero.gob.mx/2022/04/24/desaparecida-natalia-gonzalez-martinez/", 7 * 24 * time.Hour},
+		{"https://fiscaliaguerrero.gob.mx/category/alba/page/2/", 0},
+		{"https://fiscaliamorelos.gob.mx/cedulas/3/", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.rawUrl, func(t *testing.T) {
+			u, _ := url.Parse(tc.rawUrl)
+			if got := wordPressPostMaxAge(u); got != tc.wanted {
+				t.Errorf("got %s; want %s", got, tc.wanted)
+			}
+		})
+	}
+}
@@ -1,27 +1,31 @@
 package ws
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/midir99/rastreadora/dates"
 	"github.com/midir99/rastreadora/doc"
 	"github.com/midir99/rastreadora/mpp"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"github.com/midir99/rastreadora/names"
 )
 
-func ParseGroDate(value string) (time.Time, error) {
-	content := strings.Split(value, "T")
-	if len(content) != 2 {
-		return time.Time{}, fmt.Errorf("unable to parse date %s", value)
-	}
-	date, err := time.Parse("2006-01-02", content[0])
-	if err != nil {
-		return time.Time{}, fmt.Errorf("unable to parse date %s", value)
-	}
-	return date, nil
+func init() {
+	Register(groAlbaSource{})
+	Register(groAmberSource{})
+	Register(groHasVistoASource{})
+	doc.RegisterParser("grodate", func(value string) (interface{}, error) {
+		return ParseGroDate(value)
+	})
+}
+
+// ParseGroDate parses the timestamps of the posts of Guerrero, e.g.
+// "2022-06-10T12:34:56-05:00".
+func ParseGroDate(value string) (dates.Date, error) {
+	return dates.ParseInLocation(value, mpp.StateGuerrero.Location())
 }
 
 func ParseGroFound(value string) bool {
@@ -64,11 +68,11 @@ func ParseNameSexFound(value string) (string, mpp.Sex, bool) {
 			nonEmptySegments = append(nonEmptySegments, seg)
 		}
 	}
-	name := cases.Title(language.LatinAmericanSpanish).String(value)
+	name := names.Normalize(value)
 	found := false
 	sex := mpp.Sex("")
 	if len(nonEmptySegments) == 2 {
-		name = cases.Title(language.LatinAmericanSpanish).String(strings.TrimSpace(nonEmptySegments[1]))
+		name = names.Normalize(strings.TrimSpace(nonEmptySegments[1]))
 		foundLegend := strings.TrimSpace(nonEmptySegments[0])
 		sex = ParseGroSex(foundLegend)
 		found = ParseGroFound(foundLegend)
@@ -80,43 +84,80 @@ func MakeGroAlbaUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://fiscaliaguerrero.gob.mx/category/alba/page/%d/", pageNum)
 }
 
+// groPostEntry is an entry of the WordPress listings of the fiscalía of
+// Guerrero, its title tells the name and whether the person was found. The
+// metadata of the post is preferred for the poster and the publication
+// date, see enrichWithMetadata.
+type groPostEntry struct {
+	Title           string     `css:"h2 a" required:"true"`
+	PostUrl         *url.URL   `css:"h2 a" attr:"href" abs:"true" required:"true"`
+	PublicationDate dates.Date `css:".entry-date.published; .entry-date" attr:"datetime" parse:"grodate"`
+	PosterUrl       *url.URL   `css:"a" attr:"data-src" parse:"wpfullsize" abs:"true"`
+}
+
 func ScrapeGroAlbaAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
 	for i, article := range d.QueryAll(".article_content") {
-		foundAndName := strings.TrimSpace(article.Query("h2 a").Text())
-		mpName, _, found := ParseNameSexFound(foundAndName)
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
+		entry := groPostEntry{}
+		if err := unmarshalEntry(article, &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		poPostUrl, err := url.Parse(strings.TrimSpace(article.Query("h2 a").AttrOr("href", "")))
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
+		mpName, _, found := ParseNameSexFound(entry.Title)
+		if mpName == "" {
+			errs[i+1] = fmt.Errorf("MpName can't be empty")
 			continue
 		}
-		pubDate := article.Query(".entry-date.published").AttrOr("datetime", "")
-		if pubDate == "" {
-			pubDate = article.Query(".entry-date").AttrOr("datetime", "")
+		missing := mpp.MissingPersonPoster{
+			AlertType:   mpp.AlertTypeAlba,
+			Found:       found,
+			MpSex:       mpp.SexFemale,
+			PoPosterUrl: entry.PosterUrl,
+			PoPostUrl:   entry.PostUrl,
+			PoState:     mpp.StateGuerrero,
 		}
-		poPostPublicationDate, _ := ParseGroDate(pubDate)
-		posterUrl := strings.TrimSpace(article.Query("a").AttrOr("data-src", ""))
-		posterUrl = strings.Replace(posterUrl, "-480x320", "", 1)
-		poPosterUrl, _ := url.Parse(posterUrl)
-		mpps = append(mpps, mpp.MissingPersonPoster{
-			AlertType:             mpp.AlertTypeAlba,
-			Found:                 found,
-			MpName:                mpName,
-			MpSex:                 mpp.SexFemale,
-			PoPosterUrl:           poPosterUrl,
-			PoPostPublicationDate: poPostPublicationDate,
-			PoPostUrl:             poPostUrl,
-			PoState:               mpp.StateGuerrero,
-		})
+		missing.SetName(mpName)
+		missing.SetPostPublicationDate(entry.PublicationDate)
+		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
 
+type groAlbaSource struct{}
+
+func (groAlbaSource) Name() string {
+	return "gro-alba"
+}
+
+func (groAlbaSource) State() mpp.State {
+	return mpp.StateGuerrero
+}
+
+func (groAlbaSource) AlertType() mpp.AlertType {
+	return mpp.AlertTypeAlba
+}
+
+func (groAlbaSource) MakeUrl(pageNum uint64) string {
+	return MakeGroAlbaUrl(pageNum)
+}
+
+func (groAlbaSource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeGroAlbaAlerts(d)
+}
+
+func (groAlbaSource) LastPage(d *doc.Doc) bool {
+	return !hasNextPageLink(d)
+}
+
+func (groAlbaSource) CacheMaxAge(u *url.URL) time.Duration {
+	return wordPressPostMaxAge(u)
+}
+
+func (groAlbaSource) Enrich(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error {
+	return enrichWithMetadata(ctx, f, m)
+}
+
 func MakeGroAmberUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://fiscaliaguerrero.gob.mx/category/amber/page/%d/", pageNum)
 }
@@ -125,78 +166,121 @@ func ScrapeGroAmberAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error)
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
 	for i, article := range d.QueryAll(".article_content") {
-		foundAndName := strings.TrimSpace(article.Query("h2 a").Text())
-		mpName, mpSex, found := ParseNameSexFound(foundAndName)
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
+		entry := groPostEntry{}
+		if err := unmarshalEntry(article, &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		poPostUrl, err := url.Parse(strings.TrimSpace(article.Query("h2 a").AttrOr("href", "")))
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
+		mpName, mpSex, found := ParseNameSexFound(entry.Title)
+		if mpName == "" {
+			errs[i+1] = fmt.Errorf("MpName can't be empty")
 			continue
 		}
-		pubDate := article.Query(".entry-date.published").AttrOr("datetime", "")
-		if pubDate == "" {
-			pubDate = article.Query(".entry-date").AttrOr("datetime", "")
+		missing := mpp.MissingPersonPoster{
+			AlertType:   mpp.AlertTypeAmber,
+			Found:       found,
+			MpSex:       mpSex,
+			PoPosterUrl: entry.PosterUrl,
+			PoPostUrl:   entry.PostUrl,
+			PoState:     mpp.StateGuerrero,
 		}
-		poPostPublicationDate, _ := ParseGroDate(pubDate)
-		posterUrl := strings.TrimSpace(article.Query("a").AttrOr("data-src", ""))
-		posterUrl = strings.Replace(posterUrl, "-480x320", "", 1)
-		poPosterUrl, _ := url.Parse(posterUrl)
-		mpps = append(mpps, mpp.MissingPersonPoster{
-			AlertType:             mpp.AlertTypeAmber,
-			Found:                 found,
-			MpName:                mpName,
-			MpSex:                 mpSex,
-			PoPosterUrl:           poPosterUrl,
-			PoPostPublicationDate: poPostPublicationDate,
-			PoPostUrl:             poPostUrl,
-			PoState:               mpp.StateGuerrero,
-		})
+		missing.SetName(mpName)
+		missing.SetPostPublicationDate(entry.PublicationDate)
+		inferSex(&missing, entry.Title)
+		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
 
+type groAmberSource struct{}
+
+func (groAmberSource) Name() string {
+	return "gro-amber"
+}
+
+func (groAmberSource) State() mpp.State {
+	return mpp.StateGuerrero
+}
+
+func (groAmberSource) AlertType() mpp.AlertType {
+	return mpp.AlertTypeAmber
+}
+
+func (groAmberSource) MakeUrl(pageNum uint64) string {
+	return MakeGroAmberUrl(pageNum)
+}
+
+func (groAmberSource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeGroAmberAlerts(d)
+}
+
+func (groAmberSource) LastPage(d *doc.Doc) bool {
+	return !hasNextPageLink(d)
+}
+
+func (groAmberSource) CacheMaxAge(u *url.URL) time.Duration {
+	return wordPressPostMaxAge(u)
+}
+
+func (groAmberSource) Enrich(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error {
+	return enrichWithMetadata(ctx, f, m)
+}
+
 func MakeGroHasVistoAUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://fiscaliaguerrero.gob.mx/hasvistoa/?pagina=%d", pageNum)
 }
 
+// groHasVistoAEntry is a figure of the "Has visto a" listing of Guerrero,
+// its caption holds the name and the missing date separated by a <br>. The
+// post is the PDF of the poster, so it has no metadata to enrich with.
+type groHasVistoAEntry struct {
+	Name        string     `xpath:".//h4/text()[1]" required:"true"`
+	MissingDate dates.Date `xpath:".//h4/br/following-sibling::text()[1]" parse:"grodate"`
+	PostUrl     *url.URL   `css:"a" attr:"href" abs:"true" required:"true"`
+	PosterUrl   *url.URL   `css:"img" attr:"src" abs:"true"`
+}
+
 func ScrapeGroHasVistoAAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
 	for i, figure := range d.QueryAll("figure") {
-		h4 := figure.Query("h4")
-		mpName := cases.Title(language.LatinAmericanSpanish).String(strings.TrimSpace(h4.FirstChild.Data))
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
+		entry := groHasVistoAEntry{}
+		if err := unmarshalEntry(figure, &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		missingDate, _ := time.Parse("2006-01-02", h4.LastChild.Data)
-		postUrl := figure.Query("a").AttrOr("href", "")
-		if postUrl == "" {
-			errs[i+1] = fmt.Errorf("PoPostUrl can't be empty")
-			continue
-		}
-		poPostUrl, err := url.Parse("https://fiscaliaguerrero.gob.mx" + postUrl)
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
-			continue
-		}
-		var poPosterUrl *url.URL
-		posterUrl := figure.Query("img").AttrOr("src", "")
-		if posterUrl != "" {
-			posterUrl = "https://fiscaliaguerrero.gob.mx" + posterUrl
-			poPosterUrl, _ = url.Parse(posterUrl)
-		}
-		mpps = append(mpps, mpp.MissingPersonPoster{
+		missing := mpp.MissingPersonPoster{
 			AlertType:   mpp.AlertTypeHasVistoA,
-			MissingDate: missingDate,
-			MpName:      mpName,
-			PoPosterUrl: poPosterUrl,
-			PoPostUrl:   poPostUrl,
+			PoPosterUrl: entry.PosterUrl,
+			PoPostUrl:   entry.PostUrl,
 			PoState:     mpp.StateGuerrero,
-		})
+		}
+		missing.SetName(entry.Name)
+		missing.SetMissingDate(entry.MissingDate)
+		inferSex(&missing)
+		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
+
+type groHasVistoASource struct{}
+
+func (groHasVistoASource) Name() string {
+	return "gro-hasvistoa"
+}
+
+func (groHasVistoASource) State() mpp.State {
+	return mpp.StateGuerrero
+}
+
+func (groHasVistoASource) AlertType() mpp.AlertType {
+	return mpp.AlertTypeHasVistoA
+}
+
+func (groHasVistoASource) MakeUrl(pageNum uint64) string {
+	return MakeGroHasVistoAUrl(pageNum)
+}
+
+func (groHasVistoASource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeGroHasVistoAAlerts(d)
+}
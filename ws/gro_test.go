@@ -27,7 +27,7 @@ func TestParseNameSexFound(t *testing.T) {
 		},
 		{
 			"Fiscalía General del Estado solicita su colaboración para localizar a Milagros Gabriela Leyva Santiago.",
-			"Fiscalía General Del Estado Solicita Su Colaboración Para Localizar A Milagros Gabriela Leyva Santiago.",
+			"Fiscalía General del Estado Solicita Su Colaboración Para Localizar A Milagros Gabriela Leyva Santiago",
 			mpp.Sex(""),
 			false,
 		},
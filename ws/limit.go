@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds the load put on every host, it is shared by the listing
+// and the detail requests made through the same Client.
+type RateLimit struct {
+	// RequestsPerSecond is the maximum number of requests sent to a host
+	// per second, zero means no limit.
+	RequestsPerSecond float64
+	// MinDelay is the minimum time between two requests to the same host.
+	MinDelay time.Duration
+}
+
+func (r RateLimit) interval() time.Duration {
+	interval := r.MinDelay
+	if r.RequestsPerSecond > 0 {
+		if perRequest := time.Duration(float64(time.Second) / r.RequestsPerSecond); perRequest > interval {
+			interval = perRequest
+		}
+	}
+	return interval
+}
+
+type hostLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time
+}
+
+func newHostLimiter(r RateLimit) *hostLimiter {
+	return &hostLimiter{
+		interval: r.interval(),
+		next:     make(map[string]time.Time),
+	}
+}
+
+// Wait blocks until a request can be sent to host. Every caller reserves its
+// own slot, so concurrent callers are served one interval apart.
+func (l *hostLimiter) Wait(ctx context.Context, host string) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+	if wait := at.Sub(now); wait > 0 {
+		return sleep(ctx, wait)
+	}
+	return ctx.Err()
+}
+
+type limitingTransport struct {
+	limiter *hostLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
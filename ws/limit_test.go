@@ -0,0 +1,77 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterSpacing(t *testing.T) {
+	interval := 50 * time.Millisecond
+	limiter := newHostLimiter(RateLimit{MinDelay: interval})
+	start := time.Now()
+	var (
+		mu    sync.Mutex
+		times []time.Duration
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(context.Background(), "fiscaliaguerrero.gob.mx"); err != nil {
+				t.Errorf("got error %s; want nil", err)
+			}
+			mu.Lock()
+			times = append(times, time.Since(start))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	for i, got := range times {
+		if wanted := time.Duration(i) * interval; got < wanted {
+			t.Errorf("got request %d after %s; want at least %s", i+1, got, wanted)
+		}
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestLimitingTransportHosts(t *testing.T) {
+	interval := 300 * time.Millisecond
+	transport := &limitingTransport{
+		limiter: newHostLimiter(RateLimit{MinDelay: interval}),
+		next: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	// The second request to a host waits for the interval, the first request
+	// to another host doesn't wait for it.
+	for _, rawUrl := range []string{
+		"https://fiscaliaguerrero.gob.mx/category/alba/page/1/",
+		"https://fiscaliamorelos.gob.mx/cedulas/1/",
+		"https://fiscaliamorelos.gob.mx/cedulas/2/",
+	} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("got error %s; want nil", err)
+		}
+	}
+	if got := time.Since(start); got < interval || got >= 2*interval {
+		t.Errorf("got the requests sent in %s; want at least %s and less than %s", got, interval, 2*interval)
+	}
+}
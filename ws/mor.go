@@ -1,135 +1,201 @@
 package ws
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/midir99/rastreadora/dates"
 	"github.com/midir99/rastreadora/doc"
 	"github.com/midir99/rastreadora/mpp"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
-func ParseMorDate(value string) (time.Time, error) {
-	date := strings.Split(strings.ToLower(value), " ")
-	if len(date) != 3 {
-		return time.Time{}, fmt.Errorf("unable to parse date %s", value)
-	}
-	MONTH, DAY, YEAR := 0, 1, 2
-	var month time.Month
-	switch date[MONTH] {
-	case "enero":
-		month = time.January
-	case "febrero":
-		month = time.February
-	case "marzo":
-		month = time.March
-	case "abril":
-		month = time.April
-	case "mayo":
-		month = time.May
-	case "junio":
-		month = time.June
-	case "julio":
-		month = time.July
-	case "agosto":
-		month = time.August
-	case "septiembre":
-		month = time.September
-	case "octubre":
-		month = time.October
-	case "noviembre":
-		month = time.November
-	case "diciembre":
-		month = time.December
-	default:
-		return time.Time{}, fmt.Errorf("unable to parse date %s (invalid month: %s)", value, month)
-	}
-	day, err := strconv.ParseUint(strings.TrimSpace(strings.Replace(date[DAY], ",", "", 1)), 10, 64)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("unable to parse date %s (invalid day number: %s)", value, date[DAY])
-	}
-	year, err := strconv.ParseUint(date[YEAR], 10, 64)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("unable to parse date %s (invalid year number: %s)", value, date[YEAR])
-	}
-	return time.Date(int(year), month, int(day), 0, 0, 0, 0, time.UTC), nil
+func init() {
+	Register(morAmberSource{})
+	Register(morCustomSource{})
+	doc.RegisterParser("mordate", func(value string) (interface{}, error) {
+		return ParseMorDate(value)
+	})
+}
+
+// ParseMorDate parses the dates of the posts of Morelos, e.g. "junio 30,
+// 2022".
+func ParseMorDate(value string) (dates.Date, error) {
+	return dates.ParseInLocation(value, mpp.StateMorelos.Location())
 }
 
 func MakeMorAmberUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://fiscaliamorelos.gob.mx/category/alerta-amber/page/%d/", pageNum)
 }
 
-func ScrapeMorAmberPoPosterUrl(pageUrl string) (string, error) {
-	doc, err := RetrieveDocument(pageUrl, false)
+// ScrapeMorAmberExtraData retrieves the post of an Amber alert for its
+// poster, publication date and canonical URL. The metadata of the post is
+// preferred, the poster falls back to the featured image of the post.
+func ScrapeMorAmberExtraData(ctx context.Context, f Fetcher, pageUrl string) (*mpp.MissingPersonPoster, error) {
+	doc, err := f.RetrieveDocument(ctx, pageUrl)
 	if err != nil {
-		return "", fmt.Errorf("unable to retrieve the page %s", pageUrl)
+		return nil, fmt.Errorf("unable to retrieve the page %s: %w", pageUrl, err)
 	}
-	return doc.Query("div .post-thumb-img-content img").AttrOr("src", ""), nil
+	metadata := doc.Metadata()
+	missing := mpp.MissingPersonPoster{
+		PoPosterUrl: metadata.Image,
+		PoPostUrl:   metadata.Canonical,
+	}
+	if !metadata.Published.IsZero() {
+		missing.SetPostPublicationDate(dates.Date{Time: metadata.Published.In(mpp.StateMorelos.Location()), Precision: dates.PrecisionTime})
+	}
+	if missing.PoPosterUrl == nil {
+		entry := struct {
+			PosterUrl *url.URL `css:"div .post-thumb-img-content img" attr:"src" abs:"true" required:"true"`
+		}{}
+		if err := unmarshalEntry(doc, &entry); err != nil {
+			return nil, fmt.Errorf("can't parse PoPosterUrl: %s", err)
+		}
+		missing.PoPosterUrl = entry.PosterUrl
+	}
+	return &missing, nil
+}
+
+type morAmberEntry struct {
+	Name            string     `css:"h2 a" required:"true"`
+	PostUrl         *url.URL   `css:"a" attr:"href" abs:"true" required:"true"`
+	PublicationDate dates.Date `css:"span .published" parse:"mordate"`
 }
 
 func ScrapeMorAmberAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
 	for i, article := range d.QueryAll("article") {
-		mpName := cases.Title(language.LatinAmericanSpanish).String(strings.TrimSpace(article.Query("h2 a").Text()))
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
+		entry := morAmberEntry{}
+		if err := unmarshalEntry(article, &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		poPostUrl, err := url.Parse(strings.TrimSpace(article.Query("a").AttrOr("href", "")))
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
-			continue
+		missing := mpp.MissingPersonPoster{
+			AlertType: mpp.AlertTypeAmber,
+			PoPostUrl: entry.PostUrl,
+			PoState:   mpp.StateMorelos,
 		}
-		poPostPublicationDate, _ := ParseMorDate(strings.TrimSpace(article.Query("span .published").Text()))
-		posterUrl, _ := ScrapeMorAmberPoPosterUrl(poPostUrl.String())
-		poPosterUrl, _ := url.Parse(posterUrl)
-		mpps = append(mpps, mpp.MissingPersonPoster{
-			AlertType:             mpp.AlertTypeAmber,
-			MpName:                mpName,
-			PoPosterUrl:           poPosterUrl,
-			PoPostPublicationDate: poPostPublicationDate,
-			PoPostUrl:             poPostUrl,
-			PoState:               mpp.StateMorelos,
-		})
+		missing.SetName(entry.Name)
+		missing.SetPostPublicationDate(entry.PublicationDate)
+		inferSex(&missing, article.CleanText())
+		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
 
+type morAmberSource struct{}
+
+func (morAmberSource) Name() string {
+	return "mor-amber"
+}
+
+func (morAmberSource) State() mpp.State {
+	return mpp.StateMorelos
+}
+
+func (morAmberSource) AlertType() mpp.AlertType {
+	return mpp.AlertTypeAmber
+}
+
+func (morAmberSource) MakeUrl(pageNum uint64) string {
+	return MakeMorAmberUrl(pageNum)
+}
+
+func (morAmberSource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeMorAmberAlerts(d)
+}
+
+func (morAmberSource) LastPage(d *doc.Doc) bool {
+	return !hasNextPageLink(d)
+}
+
+func (morAmberSource) CacheMaxAge(u *url.URL) time.Duration {
+	return wordPressPostMaxAge(u)
+}
+
+func (morAmberSource) Enrich(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error {
+	if m.PoPostUrl == nil {
+		return fmt.Errorf("PoPostUrl can't be empty")
+	}
+	mppData, err := ScrapeMorAmberExtraData(ctx, f, m.PoPostUrl.String())
+	if err != nil {
+		return err
+	}
+	m.PoPosterUrl = mppData.PoPosterUrl
+	if !mppData.PoPostPublicationDate.IsZero() {
+		m.PoPostPublicationDate, m.PoPostPublicationDatePrecision = mppData.PoPostPublicationDate, mppData.PoPostPublicationDatePrecision
+	}
+	if mppData.PoPostUrl != nil {
+		m.PoPostUrl = mppData.PoPostUrl
+	}
+	return nil
+}
+
 func MakeMorCustomUrl(pageNum uint64) string {
 	return fmt.Sprintf("https://fiscaliamorelos.gob.mx/cedulas/%d/", pageNum)
 }
 
+type morCustomEntry struct {
+	Name            string     `css:"h3 a" required:"true"`
+	PostUrl         *url.URL   `css:"h3 a" attr:"href" abs:"true" required:"true"`
+	PublicationDate dates.Date `css:"span" parse:"mordate"`
+	PosterUrl       *url.URL   `css:"img" attr:"src" parse:"wpfullsize" abs:"true"`
+}
+
 func ScrapeMorCustomAlerts(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
 	mpps := []mpp.MissingPersonPoster{}
 	errs := make(map[int]error)
 	for i, article := range d.QueryAll("article") {
-		mpName := cases.Title(language.LatinAmericanSpanish).String(strings.TrimSpace(article.Query("h3 a").Text()))
-		if mpName == "" {
-			errs[i+1] = fmt.Errorf("MpName can't be empty")
+		entry := morCustomEntry{}
+		if err := unmarshalEntry(article, &entry); err != nil {
+			errs[i+1] = fmt.Errorf("the layout of the entry changed: %s", err)
 			continue
 		}
-		poPostUrl, err := url.Parse(strings.TrimSpace(article.Query("h3 a").AttrOr("href", "")))
-		if err != nil {
-			errs[i+1] = fmt.Errorf("can't parse PoPostUrl: %s", err)
-			continue
+		missing := mpp.MissingPersonPoster{
+			PoPosterUrl: entry.PosterUrl,
+			PoPostUrl:   entry.PostUrl,
+			PoState:     mpp.StateMorelos,
 		}
-		poPostPublicationDate, _ := ParseMorDate(strings.TrimSpace(article.Query("span").Text()))
-		posterUrl := strings.TrimSpace(article.Query("img").AttrOr("src", ""))
-		posterUrl = strings.Replace(posterUrl, "-300x225", "", 1)
-		posterUrl = strings.Replace(posterUrl, "-300x240", "", 1)
-		poPosterUrl, _ := url.Parse(posterUrl)
-		mpps = append(mpps, mpp.MissingPersonPoster{
-			MpName:                mpName,
-			PoPosterUrl:           poPosterUrl,
-			PoPostPublicationDate: poPostPublicationDate,
-			PoPostUrl:             poPostUrl,
-			PoState:               mpp.StateMorelos,
-		})
+		missing.SetName(entry.Name)
+		missing.SetPostPublicationDate(entry.PublicationDate)
+		inferSex(&missing, article.CleanText())
+		mpps = append(mpps, missing)
 	}
 	return mpps, errs
 }
+
+type morCustomSource struct{}
+
+func (morCustomSource) Name() string {
+	return "mor-custom"
+}
+
+func (morCustomSource) State() mpp.State {
+	return mpp.StateMorelos
+}
+
+func (morCustomSource) AlertType() mpp.AlertType {
+	return mpp.AlertType("")
+}
+
+func (morCustomSource) MakeUrl(pageNum uint64) string {
+	return MakeMorCustomUrl(pageNum)
+}
+
+func (morCustomSource) Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error) {
+	return ScrapeMorCustomAlerts(d)
+}
+
+func (morCustomSource) LastPage(d *doc.Doc) bool {
+	return !hasNextPageLink(d)
+}
+
+func (morCustomSource) CacheMaxAge(u *url.URL) time.Duration {
+	return wordPressPostMaxAge(u)
+}
+
+func (morCustomSource) Enrich(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error {
+	return enrichWithMetadata(ctx, f, m)
+}
@@ -0,0 +1,30 @@
+package ws
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/midir99/rastreadora/mpp"
+)
+
+func TestMorAmberEnrich(t *testing.T) {
+	postUrl := "https://fiscaliamorelos.gob.mx/2022/06/30/david-venancio-venancio/"
+	fetcher := fileFetcher{postUrl: "mor/amber-alert-single.html"}
+	poPostUrl, _ := url.Parse(postUrl)
+	missing := mpp.MissingPersonPoster{PoPostUrl: poPostUrl}
+	if err := (morAmberSource{}).Enrich(context.Background(), fetcher, &missing); err != nil {
+		t.Fatalf("got error %s; want nil", err)
+	}
+	wanted := "https://fiscaliamorelos.gob.mx/wp-content/uploads/2022/06/66.-AAMOR.68.2022-David-Venancio-14a.jpg"
+	if missing.PoPosterUrl == nil || missing.PoPosterUrl.String() != wanted {
+		t.Errorf("got PoPosterUrl %v; want %s", missing.PoPosterUrl, wanted)
+	}
+	if wanted := time.Date(2022, time.June, 30, 21, 55, 37, 0, time.UTC); !missing.PoPostPublicationDate.Equal(wanted) {
+		t.Errorf("got PoPostPublicationDate %s; want %s", missing.PoPostPublicationDate, wanted)
+	}
+	if missing.PoPostUrl.String() != postUrl {
+		t.Errorf("got PoPostUrl %s; want %s", missing.PoPostUrl, postUrl)
+	}
+}
@@ -0,0 +1,149 @@
+package ws
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+type RetryPolicy struct {
+	// Attempts is the maximum number of times a page is requested, values
+	// lower than 2 disable the retries.
+	Attempts int
+	// BaseDelay is the wait before the first retry, it doubles after every
+	// failed attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential wait between attempts. A request whose
+	// server asks with Retry-After to wait longer is not retried.
+	MaxDelay time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:  4,
+		BaseDelay: time.Second,
+		MaxDelay:  30 * time.Second,
+	}
+}
+
+// Backoff returns how long to wait after the failed attempt number attempt
+// (starting at 1). Half of the delay is random so that the requests to a
+// struggling server are spread out.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempt && i < 32 && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// StatusError is returned when the server answers with a status code other
+// than 200.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the wait requested by the server with the Retry-After
+	// header, at most MaxRetryAfter; zero if the header was absent or
+	// invalid.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d status code", e.StatusCode)
+}
+
+// MaxRetryAfter is the longest Retry-After honored, even when the retry
+// policy has no MaxDelay.
+const MaxRetryAfter = 5 * time.Minute
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	var wait time.Duration
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		wait = date.Sub(now)
+	}
+	if wait > MaxRetryAfter {
+		return MaxRetryAfter
+	}
+	return wait
+}
+
+// IsRetryable tells the errors that may go away by themselves (server
+// overload, dropped connections, timeouts) apart from the permanent ones
+// (missing pages, unknown hosts, invalid certificates, cancelled runs).
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+	var (
+		unknownAuthorityErr x509.UnknownAuthorityError
+		certInvalidErr      x509.CertificateInvalidError
+		hostnameErr         x509.HostnameError
+		recordHeaderErr     tls.RecordHeaderError
+	)
+	if errors.As(err, &unknownAuthorityErr) || errors.As(err, &certInvalidErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &recordHeaderErr) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// An unknown host won't appear by retrying, unlike a DNS server that
+	// doesn't answer.
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		// TLS alerts are reported as "remote error" or "local error".
+		return opErr.Op != "remote error" && opErr.Op != "local error"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetrieveDocumentRetries(t *testing.T) {
+	testCases := []struct {
+		name         string
+		statusCodes  []int
+		wantedCalls  int
+		wantedStatus int
+	}{
+		{"success after server errors", []int{503, 502, 200}, 3, 0},
+		{"not found is permanent", []int{404, 200}, 1, 404},
+		{"attempts are exhausted", []int{500, 500, 500, 200}, 3, 500},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCodes[calls])
+				calls++
+			}))
+			defer server.Close()
+			cfg := DefaultClientConfig()
+			cfg.Retry = RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+			cfg.RateLimit = RateLimit{}
+			_, err := NewClient(cfg).RetrieveDocument(context.Background(), server.URL)
+			if calls != tc.wantedCalls {
+				t.Errorf("got %d calls; want %d", calls, tc.wantedCalls)
+			}
+			var statusErr *StatusError
+			switch {
+			case tc.wantedStatus == 0 && err != nil:
+				t.Errorf("got error %s; want nil", err)
+			case tc.wantedStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tc.wantedStatus):
+				t.Errorf("got error %v; want %d status code", err, tc.wantedStatus)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, time.March, 15, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value  string
+		wanted time.Duration
+	}{
+		{"120", 2 * time.Minute},
+		{"86400", MaxRetryAfter},
+		{"Tue, 15 Mar 2022 12:00:30 GMT", 30 * time.Second},
+		{"Tue, 15 Mar 2022 11:00:00 GMT", 0},
+		{"soon", 0},
+		{"", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			if got := parseRetryAfter(tc.value, now); got != tc.wanted {
+				t.Errorf("got %s; want %s", got, tc.wanted)
+			}
+		})
+	}
+}
+
+func TestRetrieveDocumentRetryAfter(t *testing.T) {
+	testCases := []struct {
+		name         string
+		retryAfter   string
+		wantedCalls  int
+		wantedStatus int
+	}{
+		{"within the max delay", "0", 2, 0},
+		{"over the max delay", "86400", 1, 503},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.Header().Set("Retry-After", tc.retryAfter)
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+			cfg := DefaultClientConfig()
+			cfg.Retry = RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+			cfg.RateLimit = RateLimit{}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := NewClient(cfg).RetrieveDocument(ctx, server.URL)
+			if calls != tc.wantedCalls {
+				t.Errorf("got %d calls; want %d", calls, tc.wantedCalls)
+			}
+			var statusErr *StatusError
+			switch {
+			case tc.wantedStatus == 0 && err != nil:
+				t.Errorf("got error %s; want nil", err)
+			case tc.wantedStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tc.wantedStatus):
+				t.Errorf("got error %v; want %d status code", err, tc.wantedStatus)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		wanted bool
+	}{
+		{"server error", &StatusError{StatusCode: 503}, true},
+		{"not found", &StatusError{StatusCode: 404}, false},
+		{"cancelled", context.Canceled, false},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"unknown host", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "fiscalia.invalid", IsNotFound: true}}, false},
+		{"dns timeout", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", Name: "fiscaliaguerrero.gob.mx", IsTimeout: true}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryable(tc.err); got != tc.wanted {
+				t.Errorf("got %t; want %t", got, tc.wanted)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/midir99/rastreadora/doc"
+	"github.com/midir99/rastreadora/mpp"
+)
+
+// Source describes a single kind of alert published by a fiscalía: where its
+// listing pages live and how to turn one of them into missing person posters.
+type Source interface {
+	// Name is the identifier used in the command line, e.g. "gro-alba".
+	Name() string
+	State() mpp.State
+	AlertType() mpp.AlertType
+	MakeUrl(pageNum uint64) string
+	Scrape(d *doc.Doc) ([]mpp.MissingPersonPoster, map[int]error)
+}
+
+// Fetcher retrieves and parses a page. *Client is the Fetcher used in real
+// runs, tests may provide one that serves local files.
+type Fetcher interface {
+	RetrieveDocument(ctx context.Context, url string) (*doc.Doc, error)
+}
+
+// Enricher is implemented by the sources that need to visit the page of each
+// entry to complete the data found in the listing. Enrich must fetch those
+// pages with f and give up as soon as ctx is done.
+type Enricher interface {
+	Enrich(ctx context.Context, f Fetcher, m *mpp.MissingPersonPoster) error
+}
+
+// Paginator is implemented by the sources whose listing pages tell when
+// there are no more pages, e.g. by the absence of a link to the next one.
+type Paginator interface {
+	LastPage(d *doc.Doc) bool
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]Source)
+)
+
+// Register makes a source available by its name. It panics if the name is
+// empty or if a source with the same name was already registered.
+func Register(s Source) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	name := s.Name()
+	if name == "" {
+		panic("ws: Register source with empty name")
+	}
+	if _, dup := sources[name]; dup {
+		panic(fmt.Sprintf("ws: Register called twice for source %s", name))
+	}
+	sources[name] = s
+}
+
+func Lookup(name string) (Source, bool) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	s, ok := sources[name]
+	return s, ok
+}
+
+// Sources returns the registered sources sorted by name.
+func Sources() []Source {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	list := make([]Source, 0, len(sources))
+	for _, s := range sources {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+// hasNextPageLink tells whether a WordPress listing links to a next page.
+func hasNextPageLink(d *doc.Doc) bool {
+	return len(d.QueryAll("a.next.page-numbers")) > 0
+}
@@ -1,38 +1,245 @@
 package ws
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/midir99/rastreadora/doc"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
 )
 
-func MakeClient(skipVerify bool) *http.Client {
-	if skipVerify {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+const DefaultUserAgent = "rastreadora (+https://github.com/midir99/rastreadora)"
+
+type ClientConfig struct {
+	// UserAgent is sent in every request, DefaultUserAgent is used if empty.
+	UserAgent string
+	// Timeout limits the time of a single request, zero means no limit.
+	Timeout time.Duration
+	// MaxIdleConnsPerHost is the size of the pool of idle connections kept
+	// for every host, zero means http.DefaultMaxIdleConnsPerHost.
+	MaxIdleConnsPerHost int
+	// Proxy is the HTTP proxy used for every request, if nil the proxy is
+	// taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables.
+	Proxy *url.URL
+	// DisableCompression prevents requesting gzip compressed responses.
+	DisableCompression bool
+	// MaxRedirects is the number of redirects followed before giving up, zero
+	// means the default of the http package (10) and a negative number
+	// disables redirects.
+	MaxRedirects int
+	// SkipVerify skips the verification of the server's certificate chain
+	// and hostname.
+	SkipVerify bool
+	// Retry decides how many times and how often a failed request is
+	// repeated.
+	Retry RetryPolicy
+	// RateLimit bounds the requests sent to every host.
+	RateLimit RateLimit
+	// RecordDir is the directory where every exchange is saved, see
+	// Cassette. Recording is disabled if empty.
+	RecordDir string
+	// ReplayDir is a directory of recorded exchanges used instead of the
+	// network. Replaying is disabled if empty.
+	ReplayDir string
+	// CacheDir is the directory of the persistent cache of pages, the cache
+	// is disabled if empty.
+	CacheDir string
+	// CacheMaxAge tells for how long a cached page is used without asking
+	// the server whether it changed, nil means it is always revalidated.
+	CacheMaxAge func(u *url.URL) time.Duration
+	// Offline serves every page from the cache and never uses the network.
+	Offline bool
+	// Logger receives a line for every attempt, nil disables the logging.
+	Logger *log.Logger
+}
+
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		UserAgent:           DefaultUserAgent,
+		Timeout:             30 * time.Second,
+		MaxIdleConnsPerHost: 10,
+		MaxRedirects:        10,
+		Retry:               DefaultRetryPolicy(),
+		RateLimit:           RateLimit{RequestsPerSecond: 2},
+	}
+}
+
+// Client is shared by every fetch made during a run, so the connections to
+// each host are reused.
+type Client struct {
+	http      *http.Client
+	userAgent string
+	retry     RetryPolicy
+	logger    *log.Logger
+}
+
+func NewClient(cfg ClientConfig) *Client {
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != nil {
+		proxy = http.ProxyURL(cfg.Proxy)
+	}
+	tr := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableCompression:    cfg.DisableCompression,
+	}
+	if cfg.SkipVerify {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	// Only the requests that reach the network are rate limited, the ones
+	// answered from a cassette or from the cache are not.
+	var transport http.RoundTripper = &limitingTransport{
+		limiter: newHostLimiter(cfg.RateLimit),
+		next:    tr,
+	}
+	if cfg.ReplayDir != "" {
+		transport = NewReplayingTransport(cfg.ReplayDir)
+	}
+	if cfg.CacheDir != "" || cfg.Offline {
+		transport = NewCachingTransport(cfg.CacheDir, transport, CachePolicy{
+			MaxAge:  cfg.CacheMaxAge,
+			Offline: cfg.Offline,
+		})
+	}
+	// The recorder is above the cache so that the pages served from the
+	// cache are recorded too, and a cassette replays the whole run.
+	if cfg.RecordDir != "" {
+		transport = NewRecordingTransport(cfg.RecordDir, transport)
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}
+	switch maxRedirects := cfg.MaxRedirects; {
+	case maxRedirects < 0:
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	case maxRedirects > 0:
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		}
+	}
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &Client{
+		http:      client,
+		userAgent: userAgent,
+		retry:     cfg.Retry,
+		logger:    cfg.Logger,
+	}
+}
+
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, v...)
+	}
+}
+
+func (c *Client) RetrieveDocument(ctx context.Context, url string) (*doc.Doc, error) {
+	attempts := c.retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		d, charsetName, err := c.retrieveDocument(ctx, url)
+		if err == nil {
+			c.logf("GET %s: attempt %d/%d succeeded (charset %s)", url, attempt, attempts, charsetName)
+			return d, nil
+		}
+		if attempt >= attempts || !IsRetryable(err) {
+			c.logf("GET %s: attempt %d/%d failed: %s; giving up", url, attempt, attempts, err)
+			return nil, err
+		}
+		wait := c.retry.Backoff(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > wait {
+			// Retrying sooner than the server asked would only be refused
+			// again, and waiting longer than MaxDelay would stall the worker.
+			if c.retry.MaxDelay > 0 && statusErr.RetryAfter > c.retry.MaxDelay {
+				c.logf("GET %s: attempt %d/%d failed: %s; the server asked to retry in %s, giving up", url, attempt, attempts, err, statusErr.RetryAfter)
+				return nil, err
+			}
+			wait = statusErr.RetryAfter
+		}
+		c.logf("GET %s: attempt %d/%d failed: %s; retrying in %s", url, attempt, attempts, err, wait.Round(time.Millisecond))
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
 		}
-		return &http.Client{Transport: tr}
-	} else {
-		return http.DefaultClient
 	}
 }
 
-func RetrieveDocument(url string, skipVerify bool) (*doc.Doc, error) {
-	client := MakeClient(skipVerify)
-	resp, err := client.Get(url)
+// retrieveDocument makes a single attempt to retrieve the page, it also
+// returns the name of the charset the page was decoded from.
+func (c *Client) retrieveDocument(ctx context.Context, url string) (*doc.Doc, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%d status code", resp.StatusCode)
+		statusErr := &StatusError{StatusCode: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			statusErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		return nil, "", statusErr
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	body, charsetName, err := DecodeHTML(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", err
+	}
+	node, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	// The links are relative to the last URL of the redirect chain.
+	return doc.New(node, resp.Request.URL), charsetName, nil
+}
+
+// DecodeHTML transcodes an HTML page to UTF-8. The encoding is taken from the
+// BOM, the Content-Type header or the <meta charset> tag, in that order; if
+// none is found it is guessed from the content.
+func DecodeHTML(body []byte, contentType string) ([]byte, string, error) {
+	enc, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" {
+		return bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), name, nil
 	}
-	node, err := html.Parse(resp.Body)
+	decoded, err := enc.NewDecoder().Bytes(body)
 	if err != nil {
-		return nil, err
+		return nil, name, fmt.Errorf("unable to decode the page from %s: %s", name, err)
 	}
-	return &doc.Doc{Node: node}, nil
+	return decoded, name, nil
 }
@@ -0,0 +1,76 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/midir99/rastreadora/doc"
+	"golang.org/x/net/html"
+)
+
+// fileFetcher serves the pages of a map of URLs to files in testdata/html.
+type fileFetcher map[string]string
+
+func (f fileFetcher) RetrieveDocument(ctx context.Context, rawUrl string) (*doc.Doc, error) {
+	filename, ok := f[rawUrl]
+	if !ok {
+		return nil, fmt.Errorf("404 status code")
+	}
+	file, err := os.Open("testdata/html/" + filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	node, err := html.Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	return doc.New(node, u), nil
+}
+
+func loadDoc(t testing.TB, filename string) *doc.Doc {
+	t.Helper()
+	d, err := fileFetcher{"": filename}.RetrieveDocument(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDecodeHTML(t *testing.T) {
+	latin1 := "<html><head><meta charset=\"iso-8859-1\"></head><body>Mar\xeda Jos\xe9 Pe\xf1a</body></html>"
+	testCases := []struct {
+		name          string
+		body          string
+		contentType   string
+		wantedCharset string
+	}{
+		{"header", "<html><body>Mar\xeda Jos\xe9 Pe\xf1a</body></html>", "text/html; charset=ISO-8859-1", "windows-1252"},
+		{"meta", latin1, "text/html", "windows-1252"},
+		{"bom", "\xef\xbb\xbf<html><body>María José Peña</body></html>", "", "utf-8"},
+		{"utf-8", "<html><body>María José Peña</body></html>", "text/html; charset=utf-8", "utf-8"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, charsetName, err := DecodeHTML([]byte(tc.body), tc.contentType)
+			if err != nil {
+				t.Fatalf("got error %s; want nil", err)
+			}
+			if charsetName != tc.wantedCharset {
+				t.Errorf("got charset %s; want %s", charsetName, tc.wantedCharset)
+			}
+			node, _ := html.Parse(bytes.NewReader(body))
+			if got := (&doc.Doc{Node: node}).Query("body").Text(); got != "María José Peña" {
+				t.Errorf("got %q; want %q", got, "María José Peña")
+			}
+		})
+	}
+}